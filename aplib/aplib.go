@@ -1,5 +1,29 @@
 package aplib
 
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// HEADER_SIZE AP32 header的最小长度, HeaderSize更大时之后的部分为扩展数据
+const HEADER_SIZE = 24
+
+var (
+	ErrInvalidHeader     = fmt.Errorf("the AP32 header is invalid")
+	ErrInvalidHeaderSize = fmt.Errorf("the AP32 header size is invalid")
+	ErrInvalidPackedSize = fmt.Errorf("the packed size in the AP32 header exceeds the input")
+	ErrPackedSize        = fmt.Errorf("packed data size is incorrect")
+	ErrPackedCrc         = fmt.Errorf("packed data checksum is incorrect")
+	ErrOrigSize          = fmt.Errorf("unpacked data size is incorrect")
+	ErrOrigCrc           = fmt.Errorf("unpacked data checksum is incorrect")
+)
+
+var MagicAP32 = [4]byte{'A', 'P', '3', '2'}
+
+// AP32Header CRC为0时表示没有CRC, PackedSize为0时表示header之后的全部数据
 type AP32Header struct {
 	Magic      [4]byte
 	HeaderSize uint32
@@ -9,10 +33,238 @@ type AP32Header struct {
 	OrigCrc    uint32
 }
 
+// NewHeader 根据压缩后和压缩前的数据生成header, skipCRC时两个CRC都为0
+func NewHeader(packed, original []byte, skipCRC bool) *AP32Header {
+	header := &AP32Header{
+		Magic:      MagicAP32,
+		HeaderSize: HEADER_SIZE,
+		PackedSize: uint32(len(packed)),
+		OrigSize:   uint32(len(original)),
+	}
+	if !skipCRC {
+		header.PackedCrc = crc32.ChecksumIEEE(packed)
+		header.OrigCrc = crc32.ChecksumIEEE(original)
+	}
+	return header
+}
+
+// ParseHeader 解析input开头的AP32 header, 并校验HeaderSize和PackedSize没有超出input
+func ParseHeader(input []byte) (*AP32Header, error) {
+	if len(input) < HEADER_SIZE {
+		return nil, ErrInvalidHeader
+	}
+
+	header := &AP32Header{
+		HeaderSize: binary.LittleEndian.Uint32(input[4:]),
+		PackedSize: binary.LittleEndian.Uint32(input[8:]),
+		PackedCrc:  binary.LittleEndian.Uint32(input[12:]),
+		OrigSize:   binary.LittleEndian.Uint32(input[16:]),
+		OrigCrc:    binary.LittleEndian.Uint32(input[20:]),
+	}
+	copy(header.Magic[:], input)
+
+	if header.Magic != MagicAP32 {
+		return nil, ErrInvalidHeader
+	}
+	if header.HeaderSize < HEADER_SIZE || uint64(header.HeaderSize) > uint64(len(input)) {
+		return nil, ErrInvalidHeaderSize
+	}
+	if uint64(header.HeaderSize)+uint64(header.PackedSize) > uint64(len(input)) {
+		return nil, ErrInvalidPackedSize
+	}
+	return header, nil
+}
+
+// Bytes 返回HeaderSize字节的header, 扩展部分以0填充; HeaderSize小于HEADER_SIZE时按HEADER_SIZE处理
+func (h *AP32Header) Bytes() []byte {
+	size := int(h.HeaderSize)
+	if size < HEADER_SIZE {
+		size = HEADER_SIZE
+	}
+	buf := make([]byte, size)
+	copy(buf, h.Magic[:])
+	binary.LittleEndian.PutUint32(buf[4:], uint32(size))
+	binary.LittleEndian.PutUint32(buf[8:], h.PackedSize)
+	binary.LittleEndian.PutUint32(buf[12:], h.PackedCrc)
+	binary.LittleEndian.PutUint32(buf[16:], h.OrigSize)
+	binary.LittleEndian.PutUint32(buf[20:], h.OrigCrc)
+	return buf
+}
+
+func WriteHeader(w io.Writer, header *AP32Header) error {
+	_, err := w.Write(header.Bytes())
+	return err
+}
+
+// Packed 返回header之后的压缩数据, input需要是ParseHeader校验过的
+func (h *AP32Header) Packed(input []byte) []byte {
+	if h.PackedSize == 0 {
+		return input[h.HeaderSize:]
+	}
+	return input[h.HeaderSize : h.HeaderSize+h.PackedSize]
+}
+
 func Decompress(input []byte, strict bool) ([]byte, error) {
 	return NewDecompressor(input).Decompress(strict)
 }
 
+func DecompressWithOptions(input []byte, options Options) ([]byte, error) {
+	return NewDecompressorWithOptions(input, options).Decompress(options.Strict)
+}
+
+// DecompressSafe 与aP_depack_safe相同: 解压后的数据不能超过maxOut字节, 超过时返回ErrOutputOverflow
+func DecompressSafe(input []byte, maxOut int) ([]byte, error) {
+	if maxOut < 0 {
+		return nil, ErrOutputOverflow
+	}
+	d := NewDecompressor(input)
+	d.maxOutput = maxOut
+	d.destination = bytes.NewBuffer(make([]byte, 0, maxOut))
+	return d.Decompress(false)
+}
+
+// OrigSize 与aP_get_orig_size相同, 从AP32 header中读取解压后的大小
+func OrigSize(input []byte) (int, error) {
+	header, err := ParseHeader(input)
+	if err != nil {
+		return 0, err
+	}
+	return int(header.OrigSize), nil
+}
+
+// DecompressN 返回解压结果和使用的输入字节数, 用于确定压缩数据的结尾
+func DecompressN(input []byte, strict bool) ([]byte, int, error) {
+	return NewDecompressor(input).DecompressN(strict)
+}
+
+func DecompressNWithOptions(input []byte, options Options) ([]byte, int, error) {
+	return NewDecompressorWithOptions(input, options).DecompressN(options.Strict)
+}
+
+// SafetyMargin 计算原地解压需要的最小额外空间: 缓冲区大小为 解压后大小 + margin,
+// 正向时压缩数据放在缓冲区末尾、从开头开始输出; 反向(options.Backward)时压缩数据放在缓冲区开头、从末尾向前输出.
+// options中的Backward和Variant需要与压缩时相同
+func SafetyMargin(input []byte, options Options) (int, error) {
+	d := NewDecompressorWithOptions(input, options)
+	output, err := d.Decompress(false)
+	if err != nil {
+		return 0, err
+	}
+	// 读取第i个字节时已输出的字节数不能超过 压缩数据的起始位置 + i
+	margin := d.maxGap + len(d.source) - len(output)
+	if margin < 0 {
+		margin = 0
+	}
+	return margin, nil
+}
+
+func reverse(bs []byte) []byte {
+	result := make([]byte, len(bs))
+	for i, b := range bs {
+		result[len(bs)-1-i] = b
+	}
+	return result
+}
+
+// Level 压缩级别
+type Level struct {
+	// MaxChain 每个位置最多检查的候选位置数量
+	MaxChain int
+	// NiceLength 找到这个长度的匹配后不再继续查找
+	NiceLength int
+	// LazyDepth 贪心解析时, 使用当前位置的匹配前向后检查的位置数, 0表示不使用惰性匹配
+	LazyDepth int
+	// Optimal 按bit数计算代价的最优解析, 此时LazyDepth无效
+	Optimal bool
+}
+
+var (
+	Level1 = Level{MaxChain: 4, NiceLength: 16}
+	Level2 = Level{MaxChain: 16, NiceLength: 32}
+	Level3 = Level{MaxChain: 64, NiceLength: 128}
+	Level4 = Level{MaxChain: 256, NiceLength: 1024}
+	Level5 = Level{MaxChain: 256, NiceLength: 1024, LazyDepth: 1}
+	Level6 = Level{MaxChain: 512, NiceLength: 2048, LazyDepth: 1}
+	Level7 = Level{MaxChain: 64, NiceLength: 256, Optimal: true}
+	Level8 = Level{MaxChain: 256, NiceLength: 1024, Optimal: true}
+	Level9 = Level{MaxChain: 1024, NiceLength: 4096, Optimal: true}
+
+	DefaultLevel = Level5
+)
+
+// BitOrder tag中bit的读取顺序
+type BitOrder int
+
+const (
+	// MSBFirst 从最高位开始(aPLib)
+	MSBFirst BitOrder = iota
+	// LSBFirst 从最低位开始
+	LSBFirst
+)
+
+// Variant 压缩数据中tag的格式. 数据流中的其他部分不变, 只是每次需要新的tag时读取TagSize个字节(小端序)
+type Variant struct {
+	// TagSize tag的字节数(1, 2, 4), 0表示1
+	TagSize  int
+	BitOrder BitOrder
+}
+
+var (
+	// VariantAPLib 标准aPLib: 8位tag, 从最高位开始
+	VariantAPLib = Variant{TagSize: 1, BitOrder: MSBFirst}
+	// VariantTag16 kabopan等实现中tagsize为2的格式
+	VariantTag16 = Variant{TagSize: 2, BitOrder: MSBFirst}
+	// VariantTag32 一次读取一个DWORD作为tag(add edx, edx取bit)的修改版
+	VariantTag32 = Variant{TagSize: 4, BitOrder: MSBFirst}
+	// VariantTag8LSB 8位tag, 从最低位开始(shr取bit)的修改版
+	VariantTag8LSB = Variant{TagSize: 1, BitOrder: LSBFirst}
+	// VariantTag32LSB 32位tag, 从最低位开始的修改版
+	VariantTag32LSB = Variant{TagSize: 4, BitOrder: LSBFirst}
+)
+
+func (v Variant) tagSize() int {
+	if v.TagSize == 0 {
+		return 1
+	}
+	return v.TagSize
+}
+
+func (v Variant) valid() bool {
+	switch v.TagSize {
+	case 0, 1, 2, 4:
+	default:
+		return false
+	}
+	return v.BitOrder == MSBFirst || v.BitOrder == LSBFirst
+}
+
+type Options struct {
+	// Level 零值表示DefaultLevel
+	Level Level
+	// WindowSize 最大匹配偏移, 0表示DEFAULT_WINDOW_SIZE
+	WindowSize int
+	// Header 添加AP32 header
+	Header bool
+	// SkipCRC 不计算header中的CRC(写入0, 解压时不校验)
+	SkipCRC bool
+	// Backward 反向压缩(与apultra -b相同): 输入和压缩后的数据都是反转的, 用于从缓冲区末尾向前原地解压
+	Backward bool
+	// Variant tag的格式, 零值表示标准aPLib, 压缩和解压时需要相同
+	Variant Variant
+	// Strict 解压时校验header中的大小和CRC
+	Strict bool
+	// RejectTrailing Strict时, 压缩数据(包括header)之后还有剩余数据返回ErrTrailingData
+	RejectTrailing bool
+}
+
 func Compress(input []byte, safe bool) ([]byte, error) {
-	return NewCompressor(input).Compress(safe)
+	return CompressWithOptions(input, Options{Header: safe})
+}
+
+func CompressWithLevel(input []byte, safe bool, level Level) ([]byte, error) {
+	return CompressWithOptions(input, Options{Level: level, Header: safe})
+}
+
+func CompressWithOptions(input []byte, options Options) ([]byte, error) {
+	return NewCompressorWithOptions(input, options).Compress(options.Header)
 }
@@ -0,0 +1,363 @@
+package aplib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+// testData 可压缩的文本, 中间夹着伪随机数据
+func testData(size int) []byte {
+	data := make([]byte, size)
+	seed := uint32(1)
+	for i := range data {
+		if i%3000 < 2000 {
+			data[i] = "aPLib test data, aPLib test data "[i%33]
+		} else {
+			seed = seed*1103515245 + 12345
+			data[i] = byte(seed >> 16)
+		}
+	}
+	return data
+}
+
+// randomData 不可压缩的数据
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	seed := uint32(7)
+	for i := range data {
+		seed = seed*1103515245 + 12345
+		data[i] = byte(seed >> 16)
+	}
+	return data
+}
+
+func TestPackedSize(t *testing.T) {
+	input := bytes.Repeat([]byte("packed size "), 100)
+	compressed, err := CompressWithOptions(input, Options{Header: true, SkipCRC: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	packedSize := binary.LittleEndian.Uint32(compressed[8:])
+
+	// 结束标志在PackedSize之前
+	early := append(append([]byte(nil), compressed...), 0)
+	binary.LittleEndian.PutUint32(early[8:], packedSize+1)
+	if _, err = Decompress(early, true); err != ErrPackedSize {
+		t.Errorf("end marker before PackedSize: err = %v, expected ErrPackedSize", err)
+	}
+	if output, err := Decompress(early, false); err != nil || !bytes.Equal(output, input) {
+		t.Errorf("end marker before PackedSize, not strict: err = %v", err)
+	}
+
+	// 结束标志在PackedSize之后
+	late := append([]byte(nil), compressed...)
+	binary.LittleEndian.PutUint32(late[8:], packedSize-1)
+	if _, err = Decompress(late, true); err != ErrPackedSize {
+		t.Errorf("end marker after PackedSize: err = %v, expected ErrPackedSize", err)
+	}
+	if _, err = Decompress(late, false); err != ErrInvalidData {
+		t.Errorf("end marker after PackedSize, not strict: err = %v, expected ErrInvalidData", err)
+	}
+
+	if output, err := Decompress(compressed, true); err != nil || !bytes.Equal(output, input) {
+		t.Errorf("exact PackedSize: err = %v", err)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	input := testData(1000)
+	compressed, err := Compress(input, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := ParseHeader(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := NewHeader(compressed[HEADER_SIZE:], input, false)
+	if *header != *expected {
+		t.Fatalf("header = %+v, expected %+v", header, expected)
+	}
+	if !bytes.Equal(header.Packed(compressed), compressed[HEADER_SIZE:]) {
+		t.Fatal("Packed does not match")
+	}
+	var buf bytes.Buffer
+	if err = WriteHeader(&buf, header); err != nil || !bytes.Equal(buf.Bytes(), compressed[:HEADER_SIZE]) {
+		t.Fatalf("WriteHeader: err = %v", err)
+	}
+
+	patch := func(offset int, value uint32) []byte {
+		broken := append([]byte(nil), compressed...)
+		binary.LittleEndian.PutUint32(broken[offset:], value)
+		return broken
+	}
+	for _, test := range []struct {
+		name  string
+		input []byte
+		err   error
+	}{
+		{"short", compressed[:HEADER_SIZE-1], ErrInvalidHeader},
+		{"magic", append([]byte("AP33"), compressed[4:]...), ErrInvalidHeader},
+		{"small header size", patch(4, HEADER_SIZE-1), ErrInvalidHeaderSize},
+		{"header size beyond input", patch(4, uint32(len(compressed)+1)), ErrInvalidHeaderSize},
+		{"packed size beyond input", patch(8, uint32(len(compressed))), ErrInvalidPackedSize},
+	} {
+		if _, err = ParseHeader(test.input); err != test.err {
+			t.Errorf("%s: err = %v, expected %v", test.name, err, test.err)
+		}
+	}
+
+	// 严格模式校验header中的大小和CRC
+	for _, test := range []struct {
+		name  string
+		input []byte
+		err   error
+	}{
+		{"packed crc", patch(12, header.PackedCrc+1), ErrPackedCrc},
+		{"orig size", patch(16, header.OrigSize+1), ErrOrigSize},
+		{"orig crc", patch(20, header.OrigCrc+1), ErrOrigCrc},
+		{"packed size beyond input", patch(8, uint32(len(compressed))), ErrInvalidPackedSize},
+	} {
+		if _, err = Decompress(test.input, true); err != test.err {
+			t.Errorf("%s: err = %v, expected %v", test.name, err, test.err)
+		}
+	}
+	if _, err = Decompress(patch(20, header.OrigCrc+1), false); err != nil {
+		t.Errorf("orig crc, not strict: err = %v", err)
+	}
+
+	// 扩展的header
+	extended := &AP32Header{Magic: MagicAP32, HeaderSize: HEADER_SIZE + 8, PackedSize: header.PackedSize, OrigSize: header.OrigSize}
+	output, err := Decompress(append(extended.Bytes(), compressed[HEADER_SIZE:]...), true)
+	if err != nil || !bytes.Equal(output, input) {
+		t.Fatalf("extended header: err = %v", err)
+	}
+}
+
+func TestOrigSize(t *testing.T) {
+	input := testData(5000)
+	compressed, err := Compress(input, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, err := OrigSize(compressed); err != nil || size != len(input) {
+		t.Fatalf("size = %d, err = %v", size, err)
+	}
+	if _, err = OrigSize(compressed[4:]); err != ErrInvalidHeader {
+		t.Fatalf("no header: err = %v, expected ErrInvalidHeader", err)
+	}
+}
+
+func TestDecompressSafe(t *testing.T) {
+	input := testData(5000)
+	compressed, err := Compress(input, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		maxOut int
+		err    error
+	}{
+		{len(input), nil},
+		{len(input) + 1000, nil},
+		{len(input) - 1, ErrOutputOverflow},
+		{10, ErrOutputOverflow},
+		{-1, ErrOutputOverflow},
+	} {
+		output, err := DecompressSafe(compressed, test.maxOut)
+		if err != test.err {
+			t.Errorf("maxOut %d: err = %v, expected %v", test.maxOut, err, test.err)
+		}
+		if err == nil && !bytes.Equal(output, input) {
+			t.Errorf("maxOut %d: output does not match", test.maxOut)
+		}
+	}
+	if _, err = DecompressSafe(compressed[:len(compressed)/2], len(input)); err != ErrInvalidData {
+		t.Errorf("truncated: err = %v, expected ErrInvalidData", err)
+	}
+}
+
+func TestDecompressN(t *testing.T) {
+	input := testData(3000)
+	for _, header := range []bool{false, true} {
+		compressed, err := Compress(input, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trailing := append(append([]byte(nil), compressed...), 1, 2, 3)
+
+		for _, test := range []struct {
+			name     string
+			input    []byte
+			options  Options
+			consumed int
+			err      error
+		}{
+			{"exact", compressed, Options{Strict: true, RejectTrailing: true}, len(compressed), nil},
+			{"trailing", trailing, Options{}, len(compressed), nil},
+			{"trailing, strict", trailing, Options{Strict: true}, len(compressed), nil},
+			{"trailing, rejected", trailing, Options{Strict: true, RejectTrailing: true}, 0, ErrTrailingData},
+		} {
+			output, consumed, err := DecompressNWithOptions(test.input, test.options)
+			if err != test.err || consumed != test.consumed {
+				t.Errorf("header %v, %s: consumed = %d, err = %v, expected %d, %v",
+					header, test.name, consumed, err, test.consumed, test.err)
+			}
+			if err == nil && !bytes.Equal(output, input) {
+				t.Errorf("header %v, %s: output does not match", header, test.name)
+			}
+		}
+
+		if _, consumed, err := DecompressN(trailing, false); err != nil || consumed != len(compressed) {
+			t.Errorf("header %v: DecompressN consumed = %d, err = %v", header, consumed, err)
+		}
+	}
+}
+
+func TestBackward(t *testing.T) {
+	input := testData(10000)
+	forward, err := Compress(input, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backward, err := CompressWithOptions(input, Options{Backward: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 反向压缩的数据是反转后的输入的压缩结果再反转
+	reversed, err := Compress(reverse(input), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backward, reverse(reversed)) || bytes.Equal(backward, forward) {
+		t.Fatal("backward output is not the reversed stream")
+	}
+
+	output, err := DecompressWithOptions(backward, Options{Backward: true})
+	if err != nil || !bytes.Equal(output, input) {
+		t.Fatalf("backward round-trip: err = %v", err)
+	}
+
+	// 反向模式下的header和CRC针对反转前的数据
+	withHeader, err := CompressWithOptions(input, Options{Backward: true, Header: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := ParseHeader(withHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.OrigCrc != crc32.ChecksumIEEE(input) || header.PackedCrc != crc32.ChecksumIEEE(backward) {
+		t.Fatal("backward header CRCs do not match")
+	}
+	output, err = DecompressWithOptions(withHeader, Options{Backward: true, Strict: true})
+	if err != nil || !bytes.Equal(output, input) {
+		t.Fatalf("backward round-trip with header: err = %v", err)
+	}
+}
+
+func TestSafetyMargin(t *testing.T) {
+	for _, input := range [][]byte{testData(20000), randomData(5000)} {
+		for _, options := range []Options{
+			{},
+			{Backward: true},
+			{Variant: VariantTag16},
+			{Variant: VariantTag32LSB, Backward: true},
+		} {
+			compressed, err := CompressWithOptions(input, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			margin, err := SafetyMargin(compressed, options)
+			if err != nil {
+				t.Fatalf("%+v: %v", options, err)
+			}
+			// 不可压缩的数据至少需要 压缩后大小 - 原始大小 的额外空间
+			if margin < 0 || margin < len(compressed)-len(input) {
+				t.Errorf("%+v: margin = %d, compressed %d, original %d", options, margin, len(compressed), len(input))
+			}
+		}
+	}
+
+	// variant与压缩时不同
+	compressed, err := CompressWithOptions(randomData(1000), Options{Variant: VariantTag32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = SafetyMargin(compressed, Options{Variant: Variant{TagSize: 3}}); err != ErrInvalidVariant {
+		t.Errorf("invalid variant: err = %v, expected ErrInvalidVariant", err)
+	}
+}
+
+func TestVariants(t *testing.T) {
+	input := testData(20000)
+	variants := []Variant{{}, VariantAPLib, VariantTag16, VariantTag32, VariantTag8LSB, VariantTag32LSB}
+	var standard []byte
+	for i, variant := range variants {
+		compressed, err := CompressWithOptions(input, Options{Variant: variant})
+		if err != nil {
+			t.Fatal(err)
+		}
+		output, err := DecompressWithOptions(compressed, Options{Variant: variant})
+		if err != nil || !bytes.Equal(output, input) {
+			t.Fatalf("%+v: err = %v", variant, err)
+		}
+		switch {
+		case i == 0:
+			standard = compressed
+		case variant == VariantAPLib:
+			// 零值与标准aPLib相同
+			if !bytes.Equal(compressed, standard) {
+				t.Errorf("the zero Variant is not VariantAPLib")
+			}
+		default:
+			if bytes.Equal(compressed, standard) {
+				t.Errorf("%+v: output is the same as standard aPLib", variant)
+			}
+		}
+	}
+
+	for _, variant := range []Variant{{TagSize: 3}, {TagSize: 8}, {BitOrder: 2}} {
+		if _, err := CompressWithOptions(input, Options{Variant: variant}); err != ErrInvalidVariant {
+			t.Errorf("%+v: compress err = %v, expected ErrInvalidVariant", variant, err)
+		}
+		if _, err := DecompressWithOptions(input, Options{Variant: variant}); err != ErrInvalidVariant {
+			t.Errorf("%+v: decompress err = %v, expected ErrInvalidVariant", variant, err)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	input := append(testData(40000), randomData(3000)...)
+	sizes := make([]int, 0, 9)
+	for i, level := range []Level{Level1, Level2, Level3, Level4, Level5, Level6, Level7, Level8, Level9} {
+		for _, header := range []bool{false, true} {
+			compressed, err := CompressWithLevel(input, header, level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			output, err := Decompress(compressed, header)
+			if err != nil || !bytes.Equal(output, input) {
+				t.Fatalf("Level%d, header %v: err = %v", i+1, header, err)
+			}
+			if !header {
+				sizes = append(sizes, len(compressed))
+			}
+		}
+	}
+	// 最优解析不比贪心解析差
+	if sizes[8] > sizes[0] || sizes[8] > sizes[3] {
+		t.Errorf("sizes = %v", sizes)
+	}
+
+	// 零值表示DefaultLevel
+	defaultLevel, err := CompressWithOptions(input, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defaultLevel) != sizes[4] {
+		t.Errorf("default level size = %d, expected Level5 size %d", len(defaultLevel), sizes[4])
+	}
+}
@@ -2,29 +2,32 @@ package aplib
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
-	"hash/crc32"
 	"math/bits"
-	"unsafe"
 )
 
 type BitCompressor struct {
 	__tagSize   uint8
-	__bitBuffer uint8
+	__bitBuffer uint32
 	__tagOffset int
 	__maxBit    int
 	__bitCount  int
 	__isTagged  bool
+	__lsbFirst  bool
 
 	buffer *bytes.Buffer
 }
 
 func NewBitCompressor() *BitCompressor {
+	return NewBitCompressorWithVariant(VariantAPLib)
+}
+
+func NewBitCompressorWithVariant(variant Variant) *BitCompressor {
 	return &BitCompressor{
-		buffer:    &bytes.Buffer{},
-		__tagSize: 1,
-		__maxBit:  7, // tagSize * 8 -1
+		buffer:     &bytes.Buffer{},
+		__tagSize:  uint8(variant.tagSize()),
+		__maxBit:   variant.tagSize()*8 - 1,
+		__lsbFirst: variant.BitOrder == LSBFirst,
 	}
 }
 
@@ -68,22 +71,27 @@ func LengthDelta(offset int) int {
 func (c *BitCompressor) updateTag(end bool) {
 	// tagOffset == 0 说明还没打标签,无需写入
 	if c.__tagOffset != 0 {
-		c.buffer.Bytes()[c.__tagOffset] = c.__bitBuffer
+		tag := c.buffer.Bytes()[c.__tagOffset:]
+		for i := 0; i < int(c.__tagSize); i++ {
+			tag[i] = byte(c.__bitBuffer >> (8 * i))
+		}
 	}
 
 	// 如果不是最后一次更新, 写入下一个tag的占位符
 	if !end {
 		// 移动tagOffset到缓冲区结尾
 		c.__tagOffset = c.buffer.Len()
-		// 写入tag占位符, tagSize固定为1
-		c.writeByte(c.__tagSize)
+		// 写入tag占位符
+		for i := 0; i < int(c.__tagSize); i++ {
+			c.writeByte(0)
+		}
 	}
 }
 
 func (c *BitCompressor) writeBit(bit int) {
-	// bitCount为0说明已写满1字节或第一次写
+	// bitCount为0说明已写满一个tag或第一次写
 	if c.__bitCount == 0 {
-		// 当已满1字节时更新标签
+		// 当已满一个tag时更新标签
 		c.updateTag(false)
 		// 重置bit记录器
 		c.__bitCount = c.__maxBit
@@ -94,7 +102,11 @@ func (c *BitCompressor) writeBit(bit int) {
 	}
 
 	if bit != 0 { // 仅在存在bit位时才进行运算,
-		c.__bitBuffer |= 1 << c.__bitCount
+		if c.__lsbFirst {
+			c.__bitBuffer |= 1 << (c.__maxBit - c.__bitCount)
+		} else {
+			c.__bitBuffer |= 1 << c.__bitCount
+		}
 	}
 }
 
@@ -109,6 +121,12 @@ func (c *BitCompressor) Bytes() []byte {
 type Compressor struct {
 	*BitCompressor
 
+	finder   *MatchFinder
+	level    Level
+	skipCRC  bool
+	backward bool
+	variant  Variant
+
 	__input       []byte
 	__inputCursor int
 	__lastOffset  int
@@ -116,13 +134,50 @@ type Compressor struct {
 }
 
 func NewCompressor(input []byte) *Compressor {
+	return NewCompressorWithOptions(input, Options{})
+}
+
+func NewCompressorWithLevel(input []byte, level Level) *Compressor {
+	return NewCompressorWithOptions(input, Options{Level: level})
+}
+
+func NewCompressorWithOptions(input []byte, options Options) *Compressor {
+	level := options.Level
+	if level == (Level{}) {
+		level = DefaultLevel
+	}
+	window := options.WindowSize
+	if window <= 0 {
+		window = DEFAULT_WINDOW_SIZE
+	}
+
+	if options.Backward {
+		input = reverse(input)
+	}
+
+	finder := NewMatchFinder(input, window)
+	finder.SetLimits(level.MaxChain, level.NiceLength)
+
 	return &Compressor{
-		BitCompressor: NewBitCompressor(),
+		BitCompressor: NewBitCompressorWithVariant(options.Variant),
+		finder:        finder,
+		level:         level,
+		skipCRC:       options.SkipCRC,
+		backward:      options.Backward,
+		variant:       options.Variant,
 		__input:       input,
 		__pair:        true,
 	}
 }
 
+// SetWindowSize 设置最大匹配偏移, 小于等于0时使用DEFAULT_WINDOW_SIZE; 需要在Pack之前调用
+func (c *Compressor) SetWindowSize(window int) {
+	if window <= 0 {
+		window = DEFAULT_WINDOW_SIZE
+	}
+	c.finder.__window = window
+}
+
 /*
 false: buffer 添加当前光标的字节, 光标+1 标记为已配对
 true: 写入一个bit0(不知道是否是用于gamma判断结束的那个),  buffer 添加当前光标的字节, 光标+1 标记为已配对
@@ -165,7 +220,7 @@ func (c *Compressor) __shortBlock(offset, length int) {
 }
 
 func (c *Compressor) __block(offset, length int) {
-	if !(offset >= 2) {
+	if !(offset >= 1) {
 		panic(fmt.Sprintf("__block: offset=%d, length=%d", offset, length))
 	}
 
@@ -196,60 +251,109 @@ func (c *Compressor) __end() {
 }
 
 func (c *Compressor) Compress(safe bool) ([]byte, error) {
+	if !c.variant.valid() {
+		return nil, ErrInvalidVariant
+	}
+
 	result := c.Pack()
+	original := c.__input
+	if c.backward {
+		result = reverse(result)
+		original = reverse(original)
+	}
 
 	if safe {
-		header := AP32Header{
-			Magic:      [4]byte{'A', 'P', '3', '2'},
-			HeaderSize: 24,
-			PackedSize: uint32(len(result)),
-			PackedCrc:  crc32.ChecksumIEEE(result),
-			OrigSize:   uint32(len(c.__input)),
-			OrigCrc:    crc32.ChecksumIEEE(c.__input),
-		}
+		header := NewHeader(result, original, c.skipCRC)
+		result = append(header.Bytes(), result...)
+	}
 
-		header.HeaderSize = uint32(unsafe.Sizeof(header))
+	return result, nil
+}
 
-		headerBuf := bytes.NewBuffer(nil)
-		if err := binary.Write(headerBuf, binary.LittleEndian, &header); err != nil {
-			return nil, err
+// greedy 按匹配的偏移和长度选择token
+func (c *Compressor) greedy(offset, length int) {
+	if length == 0 { // 未找到子串时
+		if c.__input[c.__inputCursor] == 0 { // 判断当前字符是否为0x00
+			c.__singleByte(0)
+		} else {
+			c.__literal(true)
 		}
+	} else if 2 <= length && length <= 3 && 0 < offset && offset <= 127 {
+		c.__shortBlock(offset, length) // 1 1 0
+	} else if cost := blockBits(offset, length, c.__pair, c.__lastOffset); 2 <= length && cost >= 0 && cost < 9*length {
+		// 偏移较大时长度至少为 2 + LengthDelta(offset), 且只在比逐个literal更短时使用
+		c.__block(offset, length) // 1 0
+	} else if 0 <= offset && offset < 16 {
+		c.__singleByte(offset) // 1 1 1
+	} else {
+		c.__literal(true) // 0
+	}
+}
 
-		result = append(headerBuf.Bytes(), result...)
+// choose 从候选匹配中选择比逐个literal节省最多bit的一个, 窗口很大时最长的匹配不一定最合算
+func (c *Compressor) choose(matches []Match) (offset, length, saved int) {
+	for _, m := range matches {
+		cost := blockBits(m.Offset, m.Length, c.__pair, c.__lastOffset)
+		if m.Length == 1 && m.Offset < 16 {
+			cost = 3 + 4
+		} else if 2 <= m.Length && m.Length <= 3 && 0 < m.Offset && m.Offset <= 127 {
+			cost = 3 + 8
+		}
+		if cost < 0 {
+			continue
+		}
+		if s := 9*m.Length - cost; s > saved || length == 0 {
+			offset, length, saved = m.Offset, m.Length, s
+		}
 	}
+	return
+}
 
-	return result, nil
+// lazy 后面LazyDepth个位置中有节省更多的匹配时, 当前位置只输出一个字节
+func (c *Compressor) lazy(saved int) bool {
+	for k := 1; k <= c.level.LazyDepth && c.__inputCursor+k < len(c.__input); k++ {
+		if _, length, next := c.choose(c.finder.Find(c.__inputCursor + k)); length >= 2 && next > saved {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Compressor) Pack() []byte {
+	if c.level.Optimal {
+		return c.PackOptimal()
+	}
+
 	c.__literal(false)
 	for c.__inputCursor < len(c.__input) {
-		offset, length := Search(c.__input, c.__inputCursor) // 当前[cursor:cursor+length]==之前[cursor-offset:cursor-offset+length]
+		matches := c.finder.Find(c.__inputCursor)
+		offset, length, saved := c.choose(matches) // 当前[cursor:cursor+length]==之前[cursor-offset:cursor-offset+length]
 
-		// fmt.Println("cursor:", c.__inputCursor, "\t", offset, length)
-		if length == 0 { // 未找到子串时
-			if c.__input[c.__inputCursor] == 0 { // 判断当前字符是否为0x00
-				c.__singleByte(0)
+		if length >= 2 && c.lazy(saved) {
+			if matches[0].Offset < 16 {
+				c.greedy(matches[0].Offset, 1)
 			} else {
-				c.__literal(true)
+				c.greedy(0, 0)
 			}
-		} else if length == 1 && 0 <= offset && offset < 16 {
-			c.__singleByte(offset) // 1 1 1
-		} else if 2 <= length && length <= 3 && 0 < offset && offset <= 127 {
-			c.__shortBlock(offset, length) // 1 1 0
-		} else if 3 < length && 2 <= offset {
-			c.__block(offset, length) // 1 0
-		} else {
-			c.__literal(true) // 0
+			continue
 		}
+		c.greedy(offset, length)
 	}
 
 	c.__end()
 	return c.Bytes()
 }
 
+// maxWindowSize Search的查找范围, 与Compressor的窗口无关
 const maxWindowSize = 8 * 1024
 
+// DEFAULT_WINDOW_SIZE Compressor默认的最大匹配偏移. aPLib格式本身不限制偏移,
+// 偏移 >= 1280 和 >= 32000 时长度分别少编码1和2(见LengthDelta)
+const DEFAULT_WINDOW_SIZE = 1024 * 1024
+
+// Search 在cursor之前maxWindowSize字节内逐个长度查找最长的匹配.
+//
+// Deprecated: Compressor已改用MatchFinder, 它按Options的窗口查找并返回多个候选匹配, 请使用NewMatchFinder.
 func Search(buf []byte, cursor int) (offset, length int) { // py2改
 
 	//begin := time.Now()
@@ -277,6 +381,9 @@ func Search(buf []byte, cursor int) (offset, length int) { // py2改
 	return
 }
 
+// LastIndexOf 返回word在search中最后一次出现的位置, 没有时返回-1.
+//
+// Deprecated: 只被Search使用, 请使用bytes.LastIndex.
 func LastIndexOf(search, word []byte) int {
 	searchLen := len(search)
 	wordLen := len(word)
@@ -2,18 +2,33 @@ package aplib
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"hash/crc32"
 )
 
+var (
+	ErrInvalidData    = fmt.Errorf("the packed data is invalid")
+	ErrOutputOverflow = fmt.Errorf("the unpacked data exceeds the maximum output size")
+	ErrTrailingData   = fmt.Errorf("there is trailing data after the packed data")
+	ErrInvalidVariant = fmt.Errorf("the tag size or bit order is invalid")
+)
+
 type Decompressor struct {
 	header      AP32Header
 	reader      *bytes.Reader
 	source      []byte
 	destination *bytes.Buffer
-	tag         uint8
-	bitCount    int8
+	tag         uint32
+	bitCount    int
+	backward    bool
+	variant     Variant
+	// maxOutput 大于等于0时, 输出不能超过该大小(aP_depack_safe)
+	maxOutput int
+	// rejectTrailing 严格模式下, 压缩数据之后还有剩余数据时返回错误
+	rejectTrailing bool
+
+	// maxGap 读取输入时 已输出字节数 - 已读取字节数 的最大值, 用于计算原地解压的安全距离
+	maxGap int
 }
 
 func (d *Decompressor) GetBit() uint8 {
@@ -21,12 +36,20 @@ func (d *Decompressor) GetBit() uint8 {
 	d.bitCount -= 1
 	if d.bitCount < 0 {
 		// load next tag
-		d.tag = d.mustReadByte()
-		d.bitCount = 7
+		d.tag = 0
+		for i := 0; i < d.variant.tagSize(); i++ {
+			d.tag |= uint32(d.mustReadByte()) << (8 * i)
+		}
+		d.bitCount = d.variant.tagSize()*8 - 1
 	}
 
 	// shift a bit out of tag
-	bit := d.tag >> 7 & 1
+	if d.variant.BitOrder == LSBFirst {
+		bit := uint8(d.tag & 1)
+		d.tag >>= 1
+		return bit
+	}
+	bit := uint8(d.tag >> (d.variant.tagSize()*8 - 1) & 1)
 	d.tag <<= 1
 
 	return bit
@@ -46,10 +69,33 @@ func (d *Decompressor) GetGamma() int {
 }
 
 func (d *Decompressor) mustReadByte() uint8 {
+	if gap := d.destination.Len() - (len(d.source) - d.reader.Len()); gap > d.maxGap {
+		d.maxGap = gap
+	}
 	if b, err := d.reader.ReadByte(); err == nil {
 		return b
 	} else {
-		panic(err)
+		panic(ErrInvalidData)
+	}
+}
+
+func (d *Decompressor) putByte(b uint8) {
+	if d.maxOutput >= 0 && d.destination.Len() >= d.maxOutput {
+		panic(ErrOutputOverflow)
+	}
+	d.destination.WriteByte(b)
+}
+
+// copyMatch 从已输出数据中offset之前的位置复制length字节, 可以与当前位置重叠
+func (d *Decompressor) copyMatch(offset, length int) {
+	if offset <= 0 || offset > d.destination.Len() {
+		panic(ErrInvalidData)
+	}
+	if d.maxOutput >= 0 && length > d.maxOutput-d.destination.Len() {
+		panic(ErrOutputOverflow)
+	}
+	for i := 0; i < length; i++ {
+		d.destination.WriteByte(d.destination.Bytes()[d.destination.Len()-offset])
 	}
 }
 
@@ -60,7 +106,7 @@ func (d *Decompressor) dePack() []byte {
 	done := false
 
 	// first byte verbatim
-	d.destination.WriteByte(d.mustReadByte())
+	d.putByte(d.mustReadByte())
 
 	// main decompression loop
 	for !done {
@@ -73,9 +119,9 @@ func (d *Decompressor) dePack() []byte {
 					}
 
 					if offset != 0 {
-						d.destination.WriteByte(d.destination.Bytes()[d.destination.Len()-offset])
+						d.copyMatch(offset, 1)
 					} else {
-						d.destination.WriteByte(0)
+						d.putByte(0)
 					}
 
 					lwm = 0
@@ -85,9 +131,7 @@ func (d *Decompressor) dePack() []byte {
 					offset >>= 1
 
 					if offset != 0 {
-						for i := 0; i < length; i++ {
-							d.destination.WriteByte(d.destination.Bytes()[d.destination.Len()-offset])
-						}
+						d.copyMatch(offset, length)
 					} else {
 						done = true
 					}
@@ -102,9 +146,7 @@ func (d *Decompressor) dePack() []byte {
 					offset = r0
 					length := d.GetGamma()
 
-					for i := 0; i < length; i++ {
-						d.destination.WriteByte(d.destination.Bytes()[d.destination.Len()-offset])
-					}
+					d.copyMatch(offset, length)
 				} else {
 					if lwm == 0 {
 						offset -= 3
@@ -128,9 +170,7 @@ func (d *Decompressor) dePack() []byte {
 						length += 2
 					}
 
-					for i := 0; i < length; i++ {
-						d.destination.WriteByte(d.destination.Bytes()[d.destination.Len()-offset])
-					}
+					d.copyMatch(offset, length)
 
 					r0 = offset
 				}
@@ -138,7 +178,7 @@ func (d *Decompressor) dePack() []byte {
 				lwm = 1
 			}
 		} else { // 0 literal
-			d.destination.WriteByte(d.mustReadByte())
+			d.putByte(d.mustReadByte())
 			lwm = 0
 		}
 	}
@@ -147,43 +187,91 @@ func (d *Decompressor) dePack() []byte {
 }
 
 func (d *Decompressor) Decompress(strict bool) ([]byte, error) {
+	result, _, err := d.DecompressN(strict)
+	return result, err
+}
+
+// DecompressN 同时返回使用的输入字节数(包括header和结束标志), 反向模式下从输入的末尾开始计算
+func (d *Decompressor) DecompressN(strict bool) (result []byte, consumed int, err error) {
+	inputSize := len(d.source)
+	if !d.variant.valid() {
+		return nil, 0, ErrInvalidVariant
+	}
 
-	if bytes.HasPrefix(d.source, []byte("AP32")) && len(d.source) >= 24 {
+	if bytes.HasPrefix(d.source, MagicAP32[:]) && len(d.source) >= HEADER_SIZE {
 		// data has an aPLib header
-		if err := binary.Read(bytes.NewReader(d.source), binary.LittleEndian, &d.header); err != nil {
-			return nil, err
+		header, err := ParseHeader(d.source)
+		if err != nil {
+			return nil, 0, err
 		}
-		d.source = d.source[d.header.HeaderSize : d.header.HeaderSize+d.header.PackedSize]
+		d.header = *header
+		d.source = header.Packed(d.source)
 	}
 
-	if strict {
-		if d.header.PackedSize != 0 && int(d.header.PackedSize) != len(d.source) {
-			return nil, fmt.Errorf("packed data size is incorrect")
-		}
-		if d.header.PackedCrc != 0 && d.header.PackedCrc != crc32.ChecksumIEEE(d.source) {
-			return nil, fmt.Errorf("packed data checksum is incorrect")
+	if strict && d.header.PackedCrc != 0 && d.header.PackedCrc != crc32.ChecksumIEEE(d.source) {
+		return nil, 0, ErrPackedCrc
+	}
+
+	if d.backward {
+		d.source = reverse(d.source)
+	}
+
+	// 输入不完整, 偏移超出已输出的数据或输出超过maxOutput时dePack会panic
+	defer func() {
+		if r := recover(); r != nil {
+			if r != ErrInvalidData && r != ErrOutputOverflow {
+				panic(r)
+			}
+			result, consumed, err = nil, 0, r.(error)
+			// 结束标志在PackedSize之后
+			if strict && d.header.PackedSize != 0 && r == ErrInvalidData && d.reader.Len() == 0 {
+				err = ErrPackedSize
+			}
 		}
+	}()
+
+	result = d.dePack()
+	consumed = int(d.header.HeaderSize) + len(d.source) - d.reader.Len()
+
+	// 结束标志在PackedSize之前
+	if strict && d.header.PackedSize != 0 && d.reader.Len() != 0 {
+		return nil, 0, ErrPackedSize
 	}
 
-	result := d.dePack()
+	if d.backward {
+		result = reverse(result)
+	}
 
 	if strict {
 		if d.header.OrigSize != 0 && int(d.header.OrigSize) != len(result) {
-			return nil, fmt.Errorf("unpacked data size is incorrect")
+			return nil, 0, ErrOrigSize
 		}
 		if d.header.OrigCrc != 0 && d.header.OrigCrc != crc32.ChecksumIEEE(result) {
-			return nil, fmt.Errorf("unpacked data checksum is incorrect")
+			return nil, 0, ErrOrigCrc
 		}
 	}
 
-	return result, nil
+	if strict && d.rejectTrailing && consumed < inputSize {
+		return nil, 0, ErrTrailingData
+	}
+
+	return result, consumed, nil
 }
 
 func NewDecompressor(source []byte) *Decompressor {
+	return NewDecompressorWithOptions(source, Options{})
+}
+
+func NewDecompressorWithOptions(source []byte, options Options) *Decompressor {
 	return &Decompressor{
 		source:      source,
 		destination: &bytes.Buffer{},
 		tag:         0,
 		bitCount:    0,
+		backward:    options.Backward,
+		variant:     options.Variant,
+		maxOutput:   -1,
+
+		rejectTrailing: options.RejectTrailing,
 	}
 }
@@ -0,0 +1,136 @@
+package aplib
+
+// Match 当前[cursor:cursor+Length] == [cursor-Offset:cursor-Offset+Length]
+type Match struct {
+	Offset int
+	Length int
+}
+
+const (
+	// DEFAULT_MAX_CHAIN 每个位置最多检查的候选位置数量
+	DEFAULT_MAX_CHAIN = 256
+	// DEFAULT_NICE_LENGTH 找到这个长度的匹配后不再继续查找
+	DEFAULT_NICE_LENGTH = 1024
+
+	hashSize = 1 << 16
+)
+
+// MatchFinder 以2字节为键的哈希链, 按偏移从小到大遍历窗口中的候选位置
+type MatchFinder struct {
+	__input    []byte
+	__window   int
+	__maxChain int
+	__nice     int
+
+	__head     []int32
+	__prev     []int32
+	__inserted int
+}
+
+func NewMatchFinder(input []byte, window int) *MatchFinder {
+	m := &MatchFinder{
+		__input:    input,
+		__window:   window,
+		__maxChain: DEFAULT_MAX_CHAIN,
+		__nice:     DEFAULT_NICE_LENGTH,
+		__head:     make([]int32, hashSize),
+		__prev:     make([]int32, len(input)),
+	}
+	for i := range m.__head {
+		m.__head[i] = -1
+	}
+	return m
+}
+
+// SetLimits 设置哈希链的最大查找深度和足够好的匹配长度, 小于等于0时不修改
+func (m *MatchFinder) SetLimits(maxChain, nice int) {
+	if maxChain > 0 {
+		m.__maxChain = maxChain
+	}
+	if nice > 0 {
+		m.__nice = nice
+	}
+}
+
+func (m *MatchFinder) key(position int) int {
+	return int(m.__input[position])<<8 | int(m.__input[position+1])
+}
+
+// insert 将position之前的所有位置加入哈希链
+func (m *MatchFinder) insert(position int) {
+	for ; m.__inserted < position; m.__inserted++ {
+		if m.__inserted+1 >= len(m.__input) {
+			continue
+		}
+		k := m.key(m.__inserted)
+		m.__prev[m.__inserted] = m.__head[k]
+		m.__head[k] = int32(m.__inserted)
+	}
+}
+
+func (m *MatchFinder) matchLength(position, cursor, limit int) int {
+	length := 0
+	for length < limit && m.__input[position+length] == m.__input[cursor+length] {
+		length++
+	}
+	return length
+}
+
+// Find 返回cursor处的所有有用的匹配: 长度严格递增, 每个长度对应能达到该长度的最小偏移.
+// 长度为1的匹配只在偏移小于16时返回(single byte), 匹配可以与cursor重叠
+func (m *MatchFinder) Find(cursor int) []Match {
+	m.insert(cursor)
+
+	input := m.__input
+	remain := len(input) - cursor
+	if remain <= 0 {
+		return nil
+	}
+
+	var matches []Match
+	best := 0
+
+	for offset := 1; offset < 16 && offset <= cursor; offset++ {
+		if input[cursor-offset] == input[cursor] {
+			matches = append(matches, Match{Offset: offset, Length: 1})
+			best = 1
+			break
+		}
+	}
+
+	if remain < 2 {
+		return matches
+	}
+
+	limit := cursor - m.__window
+	position := int(m.__head[m.key(cursor)])
+	// 向后查看(惰性匹配)之后再查找前面的位置时, 跳过cursor之后已加入的位置
+	for position >= cursor {
+		position = int(m.__prev[position])
+	}
+	for chain := 0; position >= 0 && position >= limit && chain < m.__maxChain; chain++ {
+		// 先比较当前最优长度处的字节, 不可能更长时跳过
+		if best < 2 || (best < remain && input[position+best] == input[cursor+best]) {
+			if length := m.matchLength(position, cursor, remain); length > best {
+				best = length
+				matches = append(matches, Match{Offset: cursor - position, Length: length})
+				if length >= m.__nice || length == remain {
+					break
+				}
+			}
+		}
+		position = int(m.__prev[position])
+	}
+
+	return matches
+}
+
+// Longest 返回cursor处最长的匹配, 没有匹配时返回(0, 0)
+func (m *MatchFinder) Longest(cursor int) (offset, length int) {
+	matches := m.Find(cursor)
+	if len(matches) == 0 {
+		return 0, 0
+	}
+	match := matches[len(matches)-1]
+	return match.Offset, match.Length
+}
@@ -0,0 +1,247 @@
+package aplib
+
+import "math/bits"
+
+// 基于代价(bit数)的前向最优解析(forward arrivals):
+// 每个位置保存最多 OPTIMAL_ARRIVALS 个到达状态, 状态由上一个匹配的偏移(r0)和是否可以重用偏移(pair)区分,
+// 按块处理以限制内存; 遇到足够长的匹配时直接截断当前块并使用该匹配
+
+const (
+	OPTIMAL_ARRIVALS   = 4
+	OPTIMAL_BLOCK_SIZE = 0x10000
+	// optimalFullLengths 小于该长度时尝试每个长度, 更长的匹配只尝试最大长度
+	optimalFullLengths = 32
+)
+
+const (
+	tokenLiteral = iota
+	tokenSingleByte
+	tokenShortBlock
+	tokenBlock
+)
+
+type arrival struct {
+	cost   int
+	from   int32
+	slot   uint8
+	token  uint8
+	pair   bool
+	offset int32
+	length int32
+	r0     int32
+}
+
+// gammaBits writeVariableNumber(value)写入的bit数
+func gammaBits(value int) int {
+	return 2 * (bits.Len(uint(value)) - 1)
+}
+
+// blockBits 与__block相同的编码方式的bit数, 无法编码时返回-1
+func blockBits(offset, length int, pair bool, r0 int) int {
+	if pair && offset == r0 {
+		if length < 2 {
+			return -1
+		}
+		return 2 + gammaBits(2) + gammaBits(length)
+	}
+	n := length - LengthDelta(offset)
+	if n < 2 {
+		return -1
+	}
+	high := (offset >> 8) + 2
+	if pair {
+		high++
+	}
+	return 2 + gammaBits(high) + 8 + gammaBits(n)
+}
+
+type optimalParser struct {
+	c        *Compressor
+	arrivals [][OPTIMAL_ARRIVALS]arrival
+	counts   []int
+}
+
+func (p *optimalParser) add(index int, a arrival) {
+	slots := &p.arrivals[index]
+	count := p.counts[index]
+
+	// 相同状态只保留代价最小的
+	for i := 0; i < count; i++ {
+		if slots[i].r0 == a.r0 && slots[i].pair == a.pair {
+			if slots[i].cost <= a.cost {
+				return
+			}
+			copy(slots[i:count-1], slots[i+1:count])
+			count--
+			break
+		}
+	}
+
+	if count == OPTIMAL_ARRIVALS {
+		if slots[count-1].cost <= a.cost {
+			p.counts[index] = count
+			return
+		}
+		count--
+	}
+
+	i := count
+	for i > 0 && slots[i-1].cost > a.cost {
+		slots[i] = slots[i-1]
+		i--
+	}
+	slots[i] = a
+	p.counts[index] = count + 1
+}
+
+// relaxMatch 尝试从from的状态a使用(offset, length)的匹配到达后面的位置
+func (p *optimalParser) relaxMatch(start, from int, slot int, a *arrival, offset, length int) {
+	next := arrival{
+		from:   int32(from - start),
+		slot:   uint8(slot),
+		offset: int32(offset),
+		length: int32(length),
+		r0:     int32(offset),
+	}
+	if length <= 3 && offset <= 127 {
+		next.cost, next.token = a.cost+3+8, tokenShortBlock
+		p.add(from+length-start, next)
+	}
+	if cost := blockBits(offset, length, a.pair, int(a.r0)); cost >= 0 {
+		next.cost, next.token = a.cost+cost, tokenBlock
+		p.add(from+length-start, next)
+	}
+}
+
+func (p *optimalParser) relaxLengths(start, from, slot int, a *arrival, offset, low, high int) {
+	for length := low; length <= high; length++ {
+		if length >= optimalFullLengths && length != high {
+			length = high
+		}
+		p.relaxMatch(start, from, slot, a, offset, length)
+	}
+}
+
+// parse 解析[start, end), 返回最优路径上的token; 因长匹配截断时返回截断的位置
+func (p *optimalParser) parse(start, end int) ([]arrival, int) {
+	c := p.c
+	input := c.__input
+	size := end - start + 1
+
+	if cap(p.counts) < size {
+		p.arrivals = make([][OPTIMAL_ARRIVALS]arrival, size)
+		p.counts = make([]int, size)
+	}
+	p.arrivals = p.arrivals[:size]
+	p.counts = p.counts[:size]
+	for i := range p.counts {
+		p.counts[i] = 0
+	}
+	p.add(0, arrival{pair: c.__pair, r0: int32(c.__lastOffset)})
+
+	for i := start; i < end; i++ {
+		index := i - start
+		if p.counts[index] == 0 {
+			continue
+		}
+
+		matches := c.finder.Find(i)
+		if len(matches) > 0 && matches[len(matches)-1].Length >= c.finder.__nice && i > start {
+			end = i
+			break
+		}
+
+		remain := end - i
+		for slot := 0; slot < p.counts[index]; slot++ {
+			a := &p.arrivals[index][slot]
+
+			// literal
+			literal := arrival{cost: a.cost + 1 + 8, from: int32(index), slot: uint8(slot), token: tokenLiteral, pair: true, r0: a.r0}
+			if input[i] == 0 {
+				literal.cost, literal.token = a.cost+3+4, tokenSingleByte
+			} else {
+				for offset := 1; offset < 16 && offset <= i; offset++ {
+					if input[i-offset] == input[i] {
+						literal.cost, literal.token, literal.offset = a.cost+3+4, tokenSingleByte, int32(offset)
+						break
+					}
+				}
+			}
+			p.add(index+1, literal)
+
+			// 重用上一个偏移
+			if r0 := int(a.r0); a.pair && r0 > 0 && r0 <= i {
+				length := 0
+				for length < remain && input[i+length] == input[i+length-r0] {
+					length++
+				}
+				p.relaxLengths(start, i, slot, a, r0, 2, length)
+			}
+
+			low := 2
+			for _, m := range matches {
+				high := m.Length
+				if high > remain {
+					high = remain
+				}
+				if m.Length >= 2 {
+					p.relaxLengths(start, i, slot, a, m.Offset, low, high)
+				}
+				low = high + 1
+			}
+		}
+	}
+
+	// 回溯
+	var path []arrival
+	index, slot := end-start, 0
+	for index > 0 {
+		a := p.arrivals[index][slot]
+		path = append(path, a)
+		index, slot = int(a.from), int(a.slot)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, end
+}
+
+func (c *Compressor) emit(a arrival) {
+	switch a.token {
+	case tokenLiteral:
+		c.__literal(true)
+	case tokenSingleByte:
+		c.__singleByte(int(a.offset))
+	case tokenShortBlock:
+		c.__shortBlock(int(a.offset), int(a.length))
+	default:
+		c.__block(int(a.offset), int(a.length))
+	}
+}
+
+// PackOptimal 与Pack输出相同的格式, 使用最优解析选择token
+func (c *Compressor) PackOptimal() []byte {
+	p := &optimalParser{c: c}
+
+	c.__literal(false)
+	for c.__inputCursor < len(c.__input) {
+		start := c.__inputCursor
+		end := start + OPTIMAL_BLOCK_SIZE
+		if end > len(c.__input) {
+			end = len(c.__input)
+		}
+
+		path, cut := p.parse(start, end)
+		for _, a := range path {
+			c.emit(a)
+		}
+
+		// 截断处的长匹配
+		if cut < end {
+			c.greedy(c.finder.Longest(cut))
+		}
+	}
+
+	c.__end()
+	return c.Bytes()
+}
@@ -0,0 +1,323 @@
+package chm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CHM(ITSF)文件结构:
+//   ITSF头: 目录(ITSP)的位置和大小, 内容区(section 0)的位置
+//   ITSP头 + PMGL目录块: 每项为 名字长度, 名字(UTF-8), section, offset, length (均为ENCINT)
+//   section 0 未压缩, section 1 (MSCompressed) 为LZX压缩, 按重置间隔(reset interval)从重置表中的位置重新开始解压
+
+const (
+	SECTION_UNCOMPRESSED = 0
+	SECTION_MSCOMPRESSED = 1
+
+	ITSF_V2_HEADER_SIZE = 0x58
+	ITSF_V3_HEADER_SIZE = 0x60
+	ITSP_HEADER_SIZE    = 0x54
+	PMGL_HEADER_SIZE    = 0x14
+
+	CONTENT_PATH      = "::DataSpace/Storage/MSCompressed/Content"
+	CONTROL_DATA_PATH = "::DataSpace/Storage/MSCompressed/ControlData"
+	RESET_TABLE_PATH  = "::DataSpace/Storage/MSCompressed/Transform/{7FC28940-9D31-11D0-9B27-00A0C91E9C7C}/InstanceData/ResetTable"
+)
+
+var (
+	MagicITSF = []byte("ITSF")
+	MagicITSP = []byte("ITSP")
+	MagicPMGL = []byte("PMGL")
+	MagicLZXC = []byte("LZXC")
+)
+
+var (
+	ErrInvalidHeader    = fmt.Errorf("the CHM header is invalid")
+	ErrInvalidDirectory = fmt.Errorf("the CHM directory is invalid")
+	ErrInvalidSection   = fmt.Errorf("the CHM compressed section is invalid")
+	ErrNotFound         = fmt.Errorf("the entry is not found in the CHM directory")
+	ErrUnknownSize      = fmt.Errorf("the size of the reader is unknown, use OpenWithSize")
+)
+
+type Entry struct {
+	Name    string
+	Section int
+	Offset  int64
+	Length  int64
+}
+
+func (e *Entry) IsDir() bool {
+	return strings.HasSuffix(e.Name, "/")
+}
+
+// IsSystem 以"::"或"#"、"$"开头的内部文件
+func (e *Entry) IsSystem() bool {
+	name := strings.TrimPrefix(e.Name, "/")
+	return strings.HasPrefix(e.Name, "::") || strings.HasPrefix(name, "#") || strings.HasPrefix(name, "$")
+}
+
+type CHM struct {
+	Version       uint32
+	LanguageID    uint32
+	ChunkSize     uint32
+	Entries       []*Entry
+	ContentOffset int64
+
+	reader  io.ReaderAt
+	size    int64
+	byName  map[string]*Entry
+	section *lzxSection
+}
+
+// readEncInt 读取ENCINT: 大端序, 每字节7位, 最高位为1表示后面还有字节
+func readEncInt(data []byte, cursor *int) (int64, error) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if *cursor >= len(data) {
+			return 0, ErrInvalidDirectory
+		}
+		b := data[*cursor]
+		*cursor++
+		v = v<<7 | uint64(b&0x7F)
+		if b&0x80 == 0 {
+			return int64(v), nil
+		}
+	}
+	return 0, ErrInvalidDirectory
+}
+
+func readAt(reader io.ReaderAt, offset, length int64) ([]byte, error) {
+	buf := make([]byte, length)
+	if _, err := reader.ReadAt(buf, offset); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// readerSize 通过Size()(bytes.Reader, io.SectionReader等)或Stat()(os.File)获取reader的大小
+func readerSize(reader io.ReaderAt) (int64, error) {
+	switch r := reader.(type) {
+	case interface{ Size() int64 }:
+		return r.Size(), nil
+	case interface{ Stat() (os.FileInfo, error) }:
+		info, err := r.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return info.Size(), nil
+	}
+	return 0, ErrUnknownSize
+}
+
+// Open 打开CHM文件, reader需要能获取大小, 否则使用OpenWithSize
+func Open(reader io.ReaderAt) (*CHM, error) {
+	size, err := readerSize(reader)
+	if err != nil {
+		return nil, err
+	}
+	return OpenWithSize(reader, size)
+}
+
+// OpenWithSize 打开大小为size的CHM文件, 目录和内容的位置、大小都按size校验
+func OpenWithSize(reader io.ReaderAt, size int64) (*CHM, error) {
+	if size < ITSF_V2_HEADER_SIZE {
+		return nil, ErrInvalidHeader
+	}
+	header, err := readAt(reader, 0, ITSF_V2_HEADER_SIZE)
+	if err != nil || !bytes.Equal(header[:4], MagicITSF) {
+		return nil, ErrInvalidHeader
+	}
+
+	c := &CHM{
+		Version:    binary.LittleEndian.Uint32(header[0x04:]),
+		LanguageID: binary.LittleEndian.Uint32(header[0x14:]),
+		reader:     reader,
+		size:       size,
+		byName:     make(map[string]*Entry),
+	}
+	headerSize := binary.LittleEndian.Uint32(header[0x08:])
+	directoryOffset := int64(binary.LittleEndian.Uint64(header[0x48:]))
+	directoryLength := int64(binary.LittleEndian.Uint64(header[0x50:]))
+	if directoryOffset < 0 || directoryLength < ITSP_HEADER_SIZE ||
+		directoryLength > size || directoryOffset > size-directoryLength {
+		return nil, ErrInvalidHeader
+	}
+
+	// v2没有内容区偏移, 内容区紧跟在目录之后
+	c.ContentOffset = directoryOffset + directoryLength
+	if c.Version >= 3 && headerSize >= ITSF_V3_HEADER_SIZE {
+		extra, err := readAt(reader, ITSF_V2_HEADER_SIZE, 8)
+		if err != nil {
+			return nil, ErrInvalidHeader
+		}
+		c.ContentOffset = int64(binary.LittleEndian.Uint64(extra))
+		if c.ContentOffset < 0 || c.ContentOffset > size {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	directory, err := readAt(reader, directoryOffset, directoryLength)
+	if err != nil {
+		return nil, err
+	}
+	if err = c.parseDirectory(directory); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *CHM) parseDirectory(directory []byte) error {
+	if !bytes.Equal(directory[:4], MagicITSP) {
+		return ErrInvalidDirectory
+	}
+	headerSize := int64(binary.LittleEndian.Uint32(directory[0x08:]))
+	c.ChunkSize = binary.LittleEndian.Uint32(directory[0x10:])
+	first := int64(int32(binary.LittleEndian.Uint32(directory[0x20:])))
+	chunks := int64(binary.LittleEndian.Uint32(directory[0x2C:]))
+	chunkSize := int64(c.ChunkSize)
+
+	length := int64(len(directory))
+	if chunkSize < PMGL_HEADER_SIZE || headerSize > length || chunks > (length-headerSize)/chunkSize {
+		return ErrInvalidDirectory
+	}
+
+	visited := make(map[int64]bool)
+	for index := first; index >= 0; {
+		if index >= chunks || visited[index] {
+			return ErrInvalidDirectory
+		}
+		visited[index] = true
+
+		chunk := directory[headerSize+index*chunkSize : headerSize+(index+1)*chunkSize]
+		if !bytes.Equal(chunk[:4], MagicPMGL) {
+			return ErrInvalidDirectory
+		}
+		free := int64(binary.LittleEndian.Uint32(chunk[0x04:]))
+		if free > chunkSize-PMGL_HEADER_SIZE {
+			return ErrInvalidDirectory
+		}
+
+		entries := chunk[:chunkSize-free]
+		for cursor := PMGL_HEADER_SIZE; cursor < len(entries); {
+			nameLength, err := readEncInt(entries, &cursor)
+			if err != nil {
+				return err
+			}
+			if nameLength < 0 || int64(cursor)+nameLength > int64(len(entries)) {
+				return ErrInvalidDirectory
+			}
+			entry := &Entry{Name: string(entries[cursor : int64(cursor)+nameLength])}
+			cursor += int(nameLength)
+
+			section, err := readEncInt(entries, &cursor)
+			if err != nil {
+				return err
+			}
+			entry.Section = int(section)
+			if entry.Offset, err = readEncInt(entries, &cursor); err != nil {
+				return err
+			}
+			if entry.Length, err = readEncInt(entries, &cursor); err != nil {
+				return err
+			}
+
+			c.Entries = append(c.Entries, entry)
+			c.byName[entry.Name] = entry
+		}
+
+		index = int64(int32(binary.LittleEndian.Uint32(chunk[0x10:])))
+	}
+
+	return nil
+}
+
+// Find 按路径查找目录项, 大小写不敏感
+func (c *CHM) Find(name string) (*Entry, error) {
+	if entry, ok := c.byName[name]; ok {
+		return entry, nil
+	}
+	if !strings.HasPrefix(name, "/") && !strings.HasPrefix(name, "::") {
+		if entry, ok := c.byName["/"+name]; ok {
+			return entry, nil
+		}
+	}
+	for _, entry := range c.Entries {
+		if strings.EqualFold(entry.Name, name) || strings.EqualFold(entry.Name, "/"+name) {
+			return entry, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Files 按名字排序返回普通文件(不含目录和内部文件)
+func (c *CHM) Files() []*Entry {
+	var files []*Entry
+	for _, entry := range c.Entries {
+		if !entry.IsDir() && !entry.IsSystem() {
+			files = append(files, entry)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files
+}
+
+// OpenEntry 返回目录项内容的io.Reader
+func (c *CHM) OpenEntry(entry *Entry) (*io.SectionReader, error) {
+	switch entry.Section {
+	case SECTION_UNCOMPRESSED:
+		if entry.Offset < 0 || entry.Length < 0 || entry.Length > c.size-c.ContentOffset ||
+			entry.Offset > c.size-c.ContentOffset-entry.Length {
+			return nil, ErrInvalidDirectory
+		}
+		return io.NewSectionReader(c.reader, c.ContentOffset+entry.Offset, entry.Length), nil
+	case SECTION_MSCOMPRESSED:
+		if c.section == nil {
+			section, err := c.openSection()
+			if err != nil {
+				return nil, err
+			}
+			c.section = section
+		}
+		if entry.Offset < 0 || entry.Length < 0 || entry.Offset+entry.Length > c.section.size {
+			return nil, ErrInvalidSection
+		}
+		return io.NewSectionReader(c.section, entry.Offset, entry.Length), nil
+	}
+	return nil, ErrInvalidSection
+}
+
+// ReadFile 按路径读取整个文件
+func (c *CHM) ReadFile(name string) ([]byte, error) {
+	entry, err := c.Find(name)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := c.OpenEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	// 压缩区中的长度只受重置表中解压后大小的限制, 预分配不超过文件大小
+	capacity := entry.Length
+	if capacity > c.size {
+		capacity = c.size
+	}
+	output := bytes.NewBuffer(make([]byte, 0, capacity))
+	if _, err = io.Copy(output, reader); err != nil {
+		return nil, err
+	}
+	if int64(output.Len()) != entry.Length {
+		return nil, ErrInvalidSection
+	}
+	return output.Bytes(), nil
+}
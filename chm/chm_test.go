@@ -0,0 +1,262 @@
+package chm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/wabzsy/compression/lzx"
+)
+
+const testChunkSize = 0x1000
+
+// encInt 写入ENCINT: 大端序, 每字节7位, 除最后一个字节外最高位为1
+func encInt(v int64) []byte {
+	output := []byte{byte(v & 0x7F)}
+	for v >>= 7; v > 0; v >>= 7 {
+		output = append([]byte{byte(v&0x7F) | 0x80}, output...)
+	}
+	return output
+}
+
+type testEntry struct {
+	name    string
+	section int
+	data    []byte
+}
+
+type testCHM struct {
+	file []byte
+	// html section 0中的文件, text section 1中的文件
+	html, text []byte
+	// directoryOffset 目录在文件中的位置, contentOffset section 0在文件中的位置
+	directoryOffset, contentOffset int
+}
+
+// lzxUncompressed 一个只有未压缩块的LZX数据流(CHM格式, 没有E8转换)
+func lzxUncompressed(data []byte) []byte {
+	// 1位E8标志 + 3位块类型 + 24位块大小, 之后补齐到16位
+	header := uint32(lzx.BLOCKTYPE_UNCOMPRESSED)<<28 | uint32(len(data))<<4
+	stream := []byte{byte(header >> 16), byte(header >> 24), byte(header), byte(header >> 8)}
+	// r0, r1, r2
+	repeats := make([]byte, 12)
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint32(repeats[4*i:], 1)
+	}
+	return append(append(stream, repeats...), data...)
+}
+
+// buildCHM v3格式的CHM: 一个PMGL块, /index.html在section 0, /text.txt在section 1(两帧, 一个重置间隔)
+func buildCHM(t *testing.T) *testCHM {
+	c := &testCHM{
+		html: []byte("<html><body>hand-built CHM</body></html>"),
+		text: bytes.Repeat([]byte("0123456789abcdef"), 40000/16),
+	}
+
+	content := lzxUncompressed(c.text)
+
+	control := make([]byte, 0x1C)
+	binary.LittleEndian.PutUint32(control, 6)
+	copy(control[4:], MagicLZXC)
+	binary.LittleEndian.PutUint32(control[0x08:], 2)
+	// 重置间隔和窗口都是2帧(64KB)
+	binary.LittleEndian.PutUint32(control[0x0C:], 2)
+	binary.LittleEndian.PutUint32(control[0x10:], 2)
+
+	table := make([]byte, 0x28+2*8)
+	binary.LittleEndian.PutUint32(table, 2)
+	binary.LittleEndian.PutUint32(table[0x04:], 2)
+	binary.LittleEndian.PutUint32(table[0x08:], 8)
+	binary.LittleEndian.PutUint32(table[0x0C:], 0x28)
+	binary.LittleEndian.PutUint64(table[0x10:], uint64(len(c.text)))
+	binary.LittleEndian.PutUint64(table[0x18:], uint64(len(content)))
+	binary.LittleEndian.PutUint64(table[0x20:], lzx.FRAME_SIZE)
+	binary.LittleEndian.PutUint64(table[0x28:], 0)
+	binary.LittleEndian.PutUint64(table[0x30:], uint64(16+lzx.FRAME_SIZE))
+
+	entries := []testEntry{
+		{"/", 0, nil},
+		{"/index.html", 0, c.html},
+		{"/#SYSTEM", 0, []byte{3, 0, 0, 0}},
+		{CONTENT_PATH, 0, content},
+		{CONTROL_DATA_PATH, 0, control},
+		{RESET_TABLE_PATH, 0, table},
+		{"/text.txt", 1, c.text},
+	}
+
+	// section 0的内容和PMGL块
+	var section0 []byte
+	chunk := make([]byte, PMGL_HEADER_SIZE, testChunkSize)
+	copy(chunk, MagicPMGL)
+	binary.LittleEndian.PutUint32(chunk[0x10:], 0xFFFFFFFF)
+	for _, entry := range entries {
+		chunk = append(chunk, encInt(int64(len(entry.name)))...)
+		chunk = append(chunk, entry.name...)
+		chunk = append(chunk, encInt(int64(entry.section))...)
+		switch {
+		case entry.section == 1:
+			chunk = append(chunk, encInt(0)...)
+		case entry.data == nil:
+			chunk = append(chunk, encInt(0)...)
+		default:
+			chunk = append(chunk, encInt(int64(len(section0)))...)
+			section0 = append(section0, entry.data...)
+		}
+		chunk = append(chunk, encInt(int64(len(entry.data)))...)
+	}
+	if len(chunk) > testChunkSize {
+		t.Fatal("the directory entries do not fit in one chunk")
+	}
+	binary.LittleEndian.PutUint32(chunk[0x04:], uint32(testChunkSize-len(chunk)))
+	chunk = chunk[:testChunkSize]
+
+	directory := make([]byte, ITSP_HEADER_SIZE)
+	copy(directory, MagicITSP)
+	binary.LittleEndian.PutUint32(directory[0x04:], 1)
+	binary.LittleEndian.PutUint32(directory[0x08:], ITSP_HEADER_SIZE)
+	binary.LittleEndian.PutUint32(directory[0x10:], testChunkSize)
+	binary.LittleEndian.PutUint32(directory[0x20:], 0)
+	binary.LittleEndian.PutUint32(directory[0x24:], 0)
+	binary.LittleEndian.PutUint32(directory[0x2C:], 1)
+	directory = append(directory, chunk...)
+
+	c.directoryOffset = ITSF_V3_HEADER_SIZE
+	c.contentOffset = c.directoryOffset + len(directory)
+
+	header := make([]byte, ITSF_V3_HEADER_SIZE)
+	copy(header, MagicITSF)
+	binary.LittleEndian.PutUint32(header[0x04:], 3)
+	binary.LittleEndian.PutUint32(header[0x08:], ITSF_V3_HEADER_SIZE)
+	binary.LittleEndian.PutUint32(header[0x14:], 0x409)
+	binary.LittleEndian.PutUint64(header[0x48:], uint64(c.directoryOffset))
+	binary.LittleEndian.PutUint64(header[0x50:], uint64(len(directory)))
+	binary.LittleEndian.PutUint64(header[0x58:], uint64(c.contentOffset))
+
+	c.file = append(append(header, directory...), section0...)
+	return c
+}
+
+func TestOpen(t *testing.T) {
+	test := buildCHM(t)
+	c, err := Open(bytes.NewReader(test.file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != 3 || c.LanguageID != 0x409 || len(c.Entries) != 7 {
+		t.Fatalf("version = %d, language = %#x, entries = %d", c.Version, c.LanguageID, len(c.Entries))
+	}
+
+	files := c.Files()
+	if len(files) != 2 || files[0].Name != "/index.html" || files[1].Name != "/text.txt" {
+		t.Fatalf("files = %+v", files)
+	}
+
+	html, err := c.ReadFile("INDEX.HTML")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(html, test.html) {
+		t.Fatalf("index.html = %q", html)
+	}
+
+	text, err := c.ReadFile("/text.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(text, test.text) {
+		t.Fatal("text.txt does not match")
+	}
+
+	// 跨越帧边界的随机读取
+	reader, err := c.OpenEntry(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	part := make([]byte, 100)
+	if _, err = reader.ReadAt(part, lzx.FRAME_SIZE-50); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(part, test.text[lzx.FRAME_SIZE-50:lzx.FRAME_SIZE+50]) {
+		t.Fatal("ReadAt across frames does not match")
+	}
+
+	if _, err = c.ReadFile("missing.html"); err != ErrNotFound {
+		t.Fatalf("err = %v, expected ErrNotFound", err)
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	test := buildCHM(t)
+	size := int64(len(test.file))
+
+	// 目录超出文件结尾
+	broken := append([]byte(nil), test.file...)
+	binary.LittleEndian.PutUint64(broken[0x50:], uint64(size))
+	if _, err := Open(bytes.NewReader(broken)); err != ErrInvalidHeader {
+		t.Errorf("directory length: err = %v, expected ErrInvalidHeader", err)
+	}
+	broken = append([]byte(nil), test.file...)
+	binary.LittleEndian.PutUint64(broken[0x50:], 1<<62)
+	if _, err := Open(bytes.NewReader(broken)); err != ErrInvalidHeader {
+		t.Errorf("huge directory length: err = %v, expected ErrInvalidHeader", err)
+	}
+
+	// 内容区超出文件结尾
+	broken = append([]byte(nil), test.file...)
+	binary.LittleEndian.PutUint64(broken[0x58:], uint64(size+1))
+	if _, err := Open(bytes.NewReader(broken)); err != ErrInvalidHeader {
+		t.Errorf("content offset: err = %v, expected ErrInvalidHeader", err)
+	}
+
+	// 块数和块大小相乘会溢出
+	broken = append([]byte(nil), test.file...)
+	binary.LittleEndian.PutUint32(broken[test.directoryOffset+0x2C:], 0xFFFFFFFF)
+	if _, err := Open(bytes.NewReader(broken)); err != ErrInvalidDirectory {
+		t.Errorf("chunk count: err = %v, expected ErrInvalidDirectory", err)
+	}
+
+	// section 0中的文件超出文件结尾
+	c, err := Open(bytes.NewReader(test.file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, _ := c.Find("/index.html")
+	entry.Length = size
+	if _, err = c.ReadFile("/index.html"); err != ErrInvalidDirectory {
+		t.Errorf("entry length: err = %v, expected ErrInvalidDirectory", err)
+	}
+	entry.Length, entry.Offset = 10, -1
+	if _, err = c.ReadFile("/index.html"); err != ErrInvalidDirectory {
+		t.Errorf("entry offset: err = %v, expected ErrInvalidDirectory", err)
+	}
+
+	// 重置表中的解压后大小超过了表中的帧数
+	c, err = Open(bytes.NewReader(test.file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tableEntry, _ := c.Find(RESET_TABLE_PATH)
+	broken = append([]byte(nil), test.file...)
+	binary.LittleEndian.PutUint64(broken[int64(test.contentOffset)+tableEntry.Offset+0x10:], 1<<62)
+	if c, err = Open(bytes.NewReader(broken)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ReadFile("/text.txt"); err != ErrInvalidSection {
+		t.Errorf("section size: err = %v, expected ErrInvalidSection", err)
+	}
+
+	// 压缩区中的文件超出section的大小
+	if c, err = Open(bytes.NewReader(test.file)); err != nil {
+		t.Fatal(err)
+	}
+	text, _ := c.Find("/text.txt")
+	text.Length = 1 << 40
+	if _, err = c.ReadFile("/text.txt"); err != ErrInvalidSection {
+		t.Errorf("compressed entry length: err = %v, expected ErrInvalidSection", err)
+	}
+
+	if _, err = Open(struct{ io.ReaderAt }{bytes.NewReader(test.file)}); err != ErrUnknownSize {
+		t.Errorf("unknown size: err = %v, expected ErrUnknownSize", err)
+	}
+}
@@ -0,0 +1,174 @@
+package chm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/wabzsy/compression/lzx"
+)
+
+// ControlData (LZXC):
+//   0x00: 长度(DWORD数), 0x04: "LZXC", 0x08: 版本,
+//   0x0C: 重置间隔, 0x10: 窗口大小 (版本2以0x8000为单位, 版本1以字节为单位)
+// ResetTable:
+//   0x00: 版本, 0x04: 项数, 0x08: 项大小(8), 0x0C: 表的偏移,
+//   0x10: 解压后大小, 0x18: 压缩后大小, 0x20: 块大小, 之后每帧一项: 该帧在压缩数据中的偏移
+
+// lzxSection section 1 (MSCompressed) 的随机读取, 每次解压一个重置间隔并缓存
+type lzxSection struct {
+	reader        io.ReaderAt
+	contentOffset int64
+	contentLength int64
+	size          int64
+
+	windowBits    int
+	resetInterval int64
+	resetTable    []int64
+
+	mutex          sync.Mutex
+	cachedInterval int64
+	cached         []byte
+}
+
+func (c *CHM) openSection() (*lzxSection, error) {
+	content, err := c.Find(CONTENT_PATH)
+	if err != nil || content.Section != SECTION_UNCOMPRESSED || content.Offset < 0 || content.Length < 0 ||
+		content.Length > c.size-c.ContentOffset || content.Offset > c.size-c.ContentOffset-content.Length {
+		return nil, ErrInvalidSection
+	}
+	control, err := c.ReadFile(CONTROL_DATA_PATH)
+	if err != nil {
+		return nil, ErrInvalidSection
+	}
+	table, err := c.ReadFile(RESET_TABLE_PATH)
+	if err != nil {
+		return nil, ErrInvalidSection
+	}
+
+	if len(control) < 0x18 || !bytes.Equal(control[4:8], MagicLZXC) {
+		return nil, ErrInvalidSection
+	}
+	version := binary.LittleEndian.Uint32(control[0x08:])
+	resetInterval := int64(binary.LittleEndian.Uint32(control[0x0C:]))
+	windowSize := int64(binary.LittleEndian.Uint32(control[0x10:]))
+	if version == 2 {
+		resetInterval *= lzx.FRAME_SIZE
+		windowSize *= lzx.FRAME_SIZE
+	}
+
+	s := &lzxSection{
+		reader:         c.reader,
+		contentOffset:  c.ContentOffset + content.Offset,
+		contentLength:  content.Length,
+		resetInterval:  resetInterval,
+		windowBits:     lzx.MIN_WINDOW_BITS,
+		cachedInterval: -1,
+	}
+	for int64(1)<<s.windowBits < windowSize && s.windowBits < lzx.MAX_WINDOW_BITS {
+		s.windowBits++
+	}
+	if int64(1)<<s.windowBits != windowSize {
+		return nil, ErrInvalidSection
+	}
+	if resetInterval <= 0 || resetInterval%lzx.FRAME_SIZE != 0 {
+		return nil, ErrInvalidSection
+	}
+
+	if len(table) < 0x28 {
+		return nil, ErrInvalidSection
+	}
+	entries := int64(binary.LittleEndian.Uint32(table[0x04:]))
+	entrySize := int64(binary.LittleEndian.Uint32(table[0x08:]))
+	tableOffset := int64(binary.LittleEndian.Uint32(table[0x0C:]))
+	s.size = int64(binary.LittleEndian.Uint64(table[0x10:]))
+	if entrySize != 8 || s.size < 0 || tableOffset+entries*entrySize > int64(len(table)) {
+		return nil, ErrInvalidSection
+	}
+	// 每一帧都有一项, 解压后大小不能超过表中的帧数
+	if frames := s.size / lzx.FRAME_SIZE; frames > entries || (frames == entries && s.size%lzx.FRAME_SIZE != 0) {
+		return nil, ErrInvalidSection
+	}
+	s.resetTable = make([]int64, entries)
+	for i := range s.resetTable {
+		s.resetTable[i] = int64(binary.LittleEndian.Uint64(table[tableOffset+int64(i)*entrySize:]))
+		if s.resetTable[i] < 0 || s.resetTable[i] > s.contentLength || (i > 0 && s.resetTable[i] < s.resetTable[i-1]) {
+			return nil, ErrInvalidSection
+		}
+	}
+
+	return s, nil
+}
+
+// decompressInterval 从重置表中对应帧的位置开始解压第index个重置间隔
+func (s *lzxSection) decompressInterval(index int64) ([]byte, error) {
+	framesPerInterval := s.resetInterval / lzx.FRAME_SIZE
+	frame := index * framesPerInterval
+	if frame >= int64(len(s.resetTable)) {
+		return nil, ErrInvalidSection
+	}
+	start := s.resetTable[frame]
+	end := s.contentLength
+	if next := frame + framesPerInterval; next < int64(len(s.resetTable)) {
+		end = s.resetTable[next]
+	}
+
+	size := s.resetInterval
+	if remain := s.size - index*s.resetInterval; remain < size {
+		size = remain
+	}
+
+	compressed, err := readAt(s.reader, s.contentOffset+start, end-start)
+	if err != nil {
+		return nil, err
+	}
+
+	d := lzx.NewDecompressor(compressed, s.windowBits)
+	// E8转换使用在整个section中的位置
+	d.Offset = int(index * s.resetInterval)
+	output, err := d.Decompress(int(size))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(output)) != size {
+		return nil, ErrInvalidSection
+	}
+	return output, nil
+}
+
+func (s *lzxSection) interval(index int64) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.cachedInterval == index {
+		return s.cached, nil
+	}
+	data, err := s.decompressInterval(index)
+	if err != nil {
+		return nil, err
+	}
+	s.cachedInterval, s.cached = index, data
+	return data, nil
+}
+
+func (s *lzxSection) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrInvalidSection
+	}
+
+	n := 0
+	for n < len(p) {
+		position := off + int64(n)
+		if position >= s.size {
+			return n, io.EOF
+		}
+		index := position / s.resetInterval
+		data, err := s.interval(index)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], data[position-index*s.resetInterval:])
+	}
+	return n, nil
+}
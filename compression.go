@@ -3,8 +3,15 @@ package compression
 import (
 	"github.com/wabzsy/compression/aplib"
 	"github.com/wabzsy/compression/lznt1"
+	"github.com/wabzsy/compression/lzo"
+	"github.com/wabzsy/compression/lzss"
+	"github.com/wabzsy/compression/lzvn"
+	"github.com/wabzsy/compression/mam"
+	"github.com/wabzsy/compression/nrv"
+	"github.com/wabzsy/compression/quicklz"
 	"github.com/wabzsy/compression/rtl"
 	"github.com/wabzsy/compression/xpress"
+	"github.com/wabzsy/compression/zx0"
 )
 
 func APLibCompress(source []byte) ([]byte, error) {
@@ -23,6 +30,14 @@ func APLibStrictDecompress(source []byte) ([]byte, error) {
 	return aplib.Decompress(source, true)
 }
 
+func APLibFastCompress(source []byte) ([]byte, error) {
+	return aplib.CompressWithOptions(source, aplib.Options{Level: aplib.Level1})
+}
+
+func APLibOptimalCompress(source []byte) ([]byte, error) {
+	return aplib.CompressWithOptions(source, aplib.Options{Level: aplib.Level9})
+}
+
 func LZNT1Compress(source []byte) ([]byte, error) {
 	return lznt1.Compress(source)
 }
@@ -39,6 +54,10 @@ func XPressDecompress(source []byte) ([]byte, error) {
 	return xpress.Decompress(source)
 }
 
+func XPressOptimalCompress(source []byte) ([]byte, error) {
+	return xpress.CompressWithLevel(source, xpress.Level10)
+}
+
 func RtlLZNT1Compress(source []byte) ([]byte, error) {
 	return rtl.LZNT1Compress(source)
 }
@@ -54,3 +73,123 @@ func RtlXPressCompress(source []byte) ([]byte, error) {
 func RtlXPressDecompress(source []byte) ([]byte, error) {
 	return rtl.XPressDecompress(source)
 }
+
+func ZX0Compress(source []byte) ([]byte, error) {
+	return zx0.Compress(source)
+}
+
+func ZX0Decompress(source []byte) ([]byte, error) {
+	return zx0.Decompress(source)
+}
+
+func ZX0ClassicCompress(source []byte) ([]byte, error) {
+	return zx0.CompressWithOptions(source, zx0.Options{Classic: true})
+}
+
+func ZX0ClassicDecompress(source []byte) ([]byte, error) {
+	return zx0.DecompressWithOptions(source, zx0.Options{Classic: true})
+}
+
+func ZX0BackwardCompress(source []byte) ([]byte, error) {
+	return zx0.CompressWithOptions(source, zx0.Options{Backward: true})
+}
+
+func ZX0BackwardDecompress(source []byte) ([]byte, error) {
+	return zx0.DecompressWithOptions(source, zx0.Options{Backward: true})
+}
+
+func ZX7Compress(source []byte) ([]byte, error) {
+	return zx0.ZX7Compress(source)
+}
+
+func ZX7Decompress(source []byte) ([]byte, error) {
+	return zx0.ZX7Decompress(source)
+}
+
+func QuickLZ1Compress(source []byte) ([]byte, error) {
+	return quicklz.Compress(source, 1)
+}
+
+func QuickLZ3Compress(source []byte) ([]byte, error) {
+	return quicklz.Compress(source, 3)
+}
+
+func QuickLZDecompress(source []byte) ([]byte, error) {
+	return quicklz.Decompress(source)
+}
+
+func LZO1XCompress(source []byte) ([]byte, error) {
+	return lzo.Compress(source)
+}
+
+func LZO1X999Compress(source []byte) ([]byte, error) {
+	return lzo.Compress999(source)
+}
+
+func LZO1XDecompress(source []byte) ([]byte, error) {
+	return lzo.Decompress(source)
+}
+
+func LZVNCompress(source []byte) ([]byte, error) {
+	return lzvn.Compress(source)
+}
+
+func LZVNDecompress(source []byte) ([]byte, error) {
+	return lzvn.Decompress(source)
+}
+
+func LZVNBlocksCompress(source []byte) ([]byte, error) {
+	return lzvn.CompressBlocks(source)
+}
+
+func LZVNBlocksDecompress(source []byte) ([]byte, error) {
+	return lzvn.DecompressBlocks(source)
+}
+
+func LZSSCompress(source []byte) ([]byte, error) {
+	return lzss.Compress(source, lzss.Okumura)
+}
+
+func LZSSDecompress(source []byte) ([]byte, error) {
+	return lzss.Decompress(source, lzss.Okumura)
+}
+
+func LZ10Compress(source []byte) ([]byte, error) {
+	return lzss.Compress(source, lzss.LZ10)
+}
+
+func LZ10Decompress(source []byte) ([]byte, error) {
+	return lzss.Decompress(source, lzss.LZ10)
+}
+
+func MAMCompress(source []byte) ([]byte, error) {
+	return mam.Compress(source)
+}
+
+func MAMDecompress(source []byte) ([]byte, error) {
+	return mam.Decompress(source)
+}
+
+func NRV2BCompress(source []byte) ([]byte, error) {
+	return nrv.Compress(source, nrv.NRV2B_LE32)
+}
+
+func NRV2BDecompress(source []byte) ([]byte, error) {
+	return nrv.Decompress(source, nrv.NRV2B_LE32)
+}
+
+func NRV2DCompress(source []byte) ([]byte, error) {
+	return nrv.Compress(source, nrv.NRV2D_LE32)
+}
+
+func NRV2DDecompress(source []byte) ([]byte, error) {
+	return nrv.Decompress(source, nrv.NRV2D_LE32)
+}
+
+func NRV2ECompress(source []byte) ([]byte, error) {
+	return nrv.Compress(source, nrv.NRV2E_LE32)
+}
+
+func NRV2EDecompress(source []byte) ([]byte, error) {
+	return nrv.Decompress(source, nrv.NRV2E_LE32)
+}
@@ -39,6 +39,38 @@ func TestAPLibStrictDecompress(t *testing.T) {
 	)
 }
 
+func TestAPLibFastCompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_aplib_fast_compressd",
+		APLibFastCompress,
+	)
+}
+
+func TestAPLibFastDecompress(t *testing.T) {
+	run(t,
+		"go_aplib_fast_compressd",
+		"go_aplib_fast_decompressd",
+		APLibDecompress,
+	)
+}
+
+func TestAPLibOptimalCompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_aplib_optimal_compressd",
+		APLibOptimalCompress,
+	)
+}
+
+func TestAPLibOptimalDecompress(t *testing.T) {
+	run(t,
+		"go_aplib_optimal_compressd",
+		"go_aplib_optimal_decompressd",
+		APLibDecompress,
+	)
+}
+
 func TestLZNT1Compress(t *testing.T) {
 	run(t,
 		"test.exe",
@@ -103,6 +135,262 @@ func TestXPressDecompress(t *testing.T) {
 	)
 }
 
+func TestXPressOptimalCompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_xpress_optimal_compressd",
+		XPressOptimalCompress,
+	)
+}
+
+func TestXPressOptimalDecompress(t *testing.T) {
+	run(t,
+		"go_xpress_optimal_compressd",
+		"go_xpress_optimal_decompressd",
+		XPressDecompress,
+	)
+}
+
+func TestZX0Compress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_zx0_compressd",
+		ZX0Compress,
+	)
+}
+
+func TestZX0Decompress(t *testing.T) {
+	run(t,
+		"go_zx0_compressd",
+		"go_zx0_decompressd",
+		ZX0Decompress,
+	)
+}
+
+func TestZX0BackwardCompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_zx0_backward_compressd",
+		ZX0BackwardCompress,
+	)
+}
+
+func TestZX0BackwardDecompress(t *testing.T) {
+	run(t,
+		"go_zx0_backward_compressd",
+		"go_zx0_backward_decompressd",
+		ZX0BackwardDecompress,
+	)
+}
+
+func TestZX7Compress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_zx7_compressd",
+		ZX7Compress,
+	)
+}
+
+func TestZX7Decompress(t *testing.T) {
+	run(t,
+		"go_zx7_compressd",
+		"go_zx7_decompressd",
+		ZX7Decompress,
+	)
+}
+
+func TestQuickLZ1Compress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_quicklz1_compressd",
+		QuickLZ1Compress,
+	)
+}
+
+func TestQuickLZ1Decompress(t *testing.T) {
+	run(t,
+		"go_quicklz1_compressd",
+		"go_quicklz1_decompressd",
+		QuickLZDecompress,
+	)
+}
+
+func TestQuickLZ3Compress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_quicklz3_compressd",
+		QuickLZ3Compress,
+	)
+}
+
+func TestQuickLZ3Decompress(t *testing.T) {
+	run(t,
+		"go_quicklz3_compressd",
+		"go_quicklz3_decompressd",
+		QuickLZDecompress,
+	)
+}
+
+func TestLZO1XCompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_lzo1x_compressd",
+		LZO1XCompress,
+	)
+}
+
+func TestLZO1XDecompress(t *testing.T) {
+	run(t,
+		"go_lzo1x_compressd",
+		"go_lzo1x_decompressd",
+		LZO1XDecompress,
+	)
+}
+
+func TestLZO1X999Compress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_lzo1x999_compressd",
+		LZO1X999Compress,
+	)
+}
+
+func TestLZO1X999Decompress(t *testing.T) {
+	run(t,
+		"go_lzo1x999_compressd",
+		"go_lzo1x999_decompressd",
+		LZO1XDecompress,
+	)
+}
+
+func TestLZVNCompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_lzvn_compressd",
+		LZVNCompress,
+	)
+}
+
+func TestLZVNDecompress(t *testing.T) {
+	run(t,
+		"go_lzvn_compressd",
+		"go_lzvn_decompressd",
+		LZVNDecompress,
+	)
+}
+
+func TestLZVNBlocksCompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_lzvn_blocks_compressd",
+		LZVNBlocksCompress,
+	)
+}
+
+func TestLZVNBlocksDecompress(t *testing.T) {
+	run(t,
+		"go_lzvn_blocks_compressd",
+		"go_lzvn_blocks_decompressd",
+		LZVNBlocksDecompress,
+	)
+}
+
+func TestLZSSCompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_lzss_compressd",
+		LZSSCompress,
+	)
+}
+
+func TestLZSSDecompress(t *testing.T) {
+	run(t,
+		"go_lzss_compressd",
+		"go_lzss_decompressd",
+		LZSSDecompress,
+	)
+}
+
+func TestLZ10Compress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_lz10_compressd",
+		LZ10Compress,
+	)
+}
+
+func TestLZ10Decompress(t *testing.T) {
+	run(t,
+		"go_lz10_compressd",
+		"go_lz10_decompressd",
+		LZ10Decompress,
+	)
+}
+
+func TestMAMCompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_mam_compressd",
+		MAMCompress,
+	)
+}
+
+func TestMAMDecompress(t *testing.T) {
+	run(t,
+		"go_mam_compressd",
+		"go_mam_decompressd",
+		MAMDecompress,
+	)
+}
+
+func TestNRV2BCompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_nrv2b_compressd",
+		NRV2BCompress,
+	)
+}
+
+func TestNRV2BDecompress(t *testing.T) {
+	run(t,
+		"go_nrv2b_compressd",
+		"go_nrv2b_decompressd",
+		NRV2BDecompress,
+	)
+}
+
+func TestNRV2DCompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_nrv2d_compressd",
+		NRV2DCompress,
+	)
+}
+
+func TestNRV2DDecompress(t *testing.T) {
+	run(t,
+		"go_nrv2d_compressd",
+		"go_nrv2d_decompressd",
+		NRV2DDecompress,
+	)
+}
+
+func TestNRV2ECompress(t *testing.T) {
+	run(t,
+		"test.exe",
+		"go_nrv2e_compressd",
+		NRV2ECompress,
+	)
+}
+
+func TestNRV2EDecompress(t *testing.T) {
+	run(t,
+		"go_nrv2e_compressd",
+		"go_nrv2e_decompressd",
+		NRV2EDecompress,
+	)
+}
+
 func sha1Sum(bs []byte) []byte {
 	s := sha1.New()
 	s.Write(bs)
@@ -26,6 +26,39 @@ func main() {
   9: Xpress Decompress (golang)
   10: RtlCompressBuffer (COMPRESSION_FORMAT_XPRESS | COMPRESSION_ENGINE_MAXIMUM) -- Windows only
   11: RtlDecompressBuffer (COMPRESSION_FORMAT_XPRESS) -- Windows only
+  12: ZX0 Compress (golang)
+  13: ZX0 Decompress (golang)
+  14: ZX0 Backward Compress (golang)
+  15: ZX0 Backward Decompress (golang)
+  16: ZX0 Classic(v1) Decompress (golang)
+  17: ZX7 Compress (golang)
+  18: ZX7 Decompress (golang)
+  19: QuickLZ Level 1 Compress (golang)
+  20: QuickLZ Level 3 Compress (golang)
+  21: QuickLZ Decompress (golang)
+  22: LZO1X-1 Compress (golang)
+  23: LZO1X-999 Compress (golang)
+  24: LZO1X Decompress (golang)
+  25: LZVN Compress (golang)
+  26: LZVN Decompress (golang)
+  27: LZVN Compress with bvxn/bvx$ blocks (golang)
+  28: LZVN Decompress bvxn/bvx-/bvx$ blocks (golang)
+  29: LZSS Compress (Okumura LZSS.C, golang)
+  30: LZSS Decompress (Okumura LZSS.C, golang)
+  31: LZSS Compress (LZ10 without header, golang)
+  32: LZSS Decompress (LZ10 without header, golang)
+  33: MAM Compress (XPRESS Huffman, golang)
+  34: MAM Decompress (Windows 10 prefetch, golang)
+  35: aPLib Compress without header, fast level (golang)
+  36: aPLib Compress without header, optimal level (golang)
+  37: Xpress Compress, optimal level (golang)
+  38: ZX0 Classic(v1) Compress (golang)
+  39: NRV2B Compress (UCL, LE32, golang)
+  40: NRV2B Decompress (UCL, LE32, golang)
+  41: NRV2D Compress (UCL, LE32, golang)
+  42: NRV2D Decompress (UCL, LE32, golang)
+  43: NRV2E Compress (UCL, LE32, golang)
+  44: NRV2E Decompress (UCL, LE32, golang)
 `)
 	flag.Parse()
 
@@ -84,6 +117,105 @@ func main() {
 	case 11:
 		// RtlDecompressBuffer (COMPRESSION_FORMAT_XPRESS)
 		result, err = compression.RtlXPressDecompress(source)
+	case 12:
+		// ZX0 Compress (golang)
+		result, err = compression.ZX0Compress(source)
+	case 13:
+		// ZX0 Decompress (golang)
+		result, err = compression.ZX0Decompress(source)
+	case 14:
+		// ZX0 Backward Compress (golang)
+		result, err = compression.ZX0BackwardCompress(source)
+	case 15:
+		// ZX0 Backward Decompress (golang)
+		result, err = compression.ZX0BackwardDecompress(source)
+	case 16:
+		// ZX0 Classic(v1) Decompress (golang)
+		result, err = compression.ZX0ClassicDecompress(source)
+	case 17:
+		// ZX7 Compress (golang)
+		result, err = compression.ZX7Compress(source)
+	case 18:
+		// ZX7 Decompress (golang)
+		result, err = compression.ZX7Decompress(source)
+	case 19:
+		// QuickLZ Level 1 Compress (golang)
+		result, err = compression.QuickLZ1Compress(source)
+	case 20:
+		// QuickLZ Level 3 Compress (golang)
+		result, err = compression.QuickLZ3Compress(source)
+	case 21:
+		// QuickLZ Decompress (golang)
+		result, err = compression.QuickLZDecompress(source)
+	case 22:
+		// LZO1X-1 Compress (golang)
+		result, err = compression.LZO1XCompress(source)
+	case 23:
+		// LZO1X-999 Compress (golang)
+		result, err = compression.LZO1X999Compress(source)
+	case 24:
+		// LZO1X Decompress (golang)
+		result, err = compression.LZO1XDecompress(source)
+	case 25:
+		// LZVN Compress (golang)
+		result, err = compression.LZVNCompress(source)
+	case 26:
+		// LZVN Decompress (golang)
+		result, err = compression.LZVNDecompress(source)
+	case 27:
+		// LZVN Compress with bvxn/bvx$ blocks (golang)
+		result, err = compression.LZVNBlocksCompress(source)
+	case 28:
+		// LZVN Decompress bvxn/bvx-/bvx$ blocks (golang)
+		result, err = compression.LZVNBlocksDecompress(source)
+	case 29:
+		// LZSS Compress (Okumura LZSS.C, golang)
+		result, err = compression.LZSSCompress(source)
+	case 30:
+		// LZSS Decompress (Okumura LZSS.C, golang)
+		result, err = compression.LZSSDecompress(source)
+	case 31:
+		// LZSS Compress (LZ10 without header, golang)
+		result, err = compression.LZ10Compress(source)
+	case 32:
+		// LZSS Decompress (LZ10 without header, golang)
+		result, err = compression.LZ10Decompress(source)
+	case 33:
+		// MAM Compress (XPRESS Huffman, golang)
+		result, err = compression.MAMCompress(source)
+	case 34:
+		// MAM Decompress (Windows 10 prefetch, golang)
+		result, err = compression.MAMDecompress(source)
+	case 35:
+		// aPLib Compress without header, fast level (golang)
+		result, err = compression.APLibFastCompress(source)
+	case 36:
+		// aPLib Compress without header, optimal level (golang)
+		result, err = compression.APLibOptimalCompress(source)
+	case 37:
+		// Xpress Compress, optimal level (golang)
+		result, err = compression.XPressOptimalCompress(source)
+	case 38:
+		// ZX0 Classic(v1) Compress (golang)
+		result, err = compression.ZX0ClassicCompress(source)
+	case 39:
+		// NRV2B Compress (UCL, LE32, golang)
+		result, err = compression.NRV2BCompress(source)
+	case 40:
+		// NRV2B Decompress (UCL, LE32, golang)
+		result, err = compression.NRV2BDecompress(source)
+	case 41:
+		// NRV2D Compress (UCL, LE32, golang)
+		result, err = compression.NRV2DCompress(source)
+	case 42:
+		// NRV2D Decompress (UCL, LE32, golang)
+		result, err = compression.NRV2DDecompress(source)
+	case 43:
+		// NRV2E Compress (UCL, LE32, golang)
+		result, err = compression.NRV2ECompress(source)
+	case 44:
+		// NRV2E Decompress (UCL, LE32, golang)
+		result, err = compression.NRV2EDecompress(source)
 	default:
 		log.Fatalln("unknown mode")
 	}
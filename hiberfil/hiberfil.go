@@ -0,0 +1,191 @@
+package hiberfil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/wabzsy/compression/xpress"
+)
+
+// Windows XP ~ 7 的hiberfil.sys中, 内存页保存在一系列Xpress块中, 每个块有0x20字节的头:
+//   0x00: "\x81\x81xpress"
+//   0x08: LE32, 低8位为 页数 - 1, 右移10位为 压缩后的大小 - 1, 数据按8字节对齐
+//   0x0C: 保留
+// 压缩后大小等于 页数 * PAGE_SIZE 的块未压缩
+//
+// 本包只处理这种格式, Windows 8及之后的压缩页集合(COMPRESSION_ENGINE_HIBER)不包含Xpress块, 返回ErrNotSupported
+
+const (
+	PAGE_SIZE        = 0x1000
+	BLOCK_HEADER_LEN = 0x20
+)
+
+var (
+	Magic = []byte("\x81\x81xpress")
+)
+
+var (
+	ErrInvalidHeader = fmt.Errorf("the xpress block header is invalid")
+	ErrInvalidData   = fmt.Errorf("the input data is invalid")
+	ErrInvalidRange  = fmt.Errorf("the memory ranges do not match the number of pages")
+	ErrNotSupported  = fmt.Errorf("the hibernation file format is not supported (no xpress blocks found)")
+)
+
+type Block struct {
+	// Offset 块头在文件中的位置
+	Offset         int64
+	Pages          int
+	CompressedSize int
+}
+
+func (b *Block) IsCompressed() bool {
+	return b.CompressedSize != b.Pages*PAGE_SIZE
+}
+
+// Next 下一个块头的位置
+func (b *Block) Next() int64 {
+	return b.Offset + BLOCK_HEADER_LEN + int64(b.CompressedSize)
+}
+
+func ParseBlockHeader(header []byte) (*Block, error) {
+	if len(header) < BLOCK_HEADER_LEN || !bytes.Equal(header[:len(Magic)], Magic) {
+		return nil, ErrInvalidHeader
+	}
+
+	size := int(binary.LittleEndian.Uint32(header[8:])>>10) + 1
+	size = (size + 7) &^ 7
+
+	return &Block{
+		Pages:          int(header[8]) + 1,
+		CompressedSize: size,
+	}, nil
+}
+
+// FindBlocks 从头开始依次遍历Xpress块, 块之间有其他数据(如内存范围表)时向后搜索下一个块头,
+// 非空的数据中找不到任何块时(Windows 8及之后的格式)返回ErrNotSupported
+func FindBlocks(data []byte) ([]*Block, error) {
+	var blocks []*Block
+
+	for offset := 0; offset < len(data); {
+		index := bytes.Index(data[offset:], Magic)
+		if index < 0 {
+			break
+		}
+		offset += index
+
+		block, err := ParseBlockHeader(data[offset:])
+		if err != nil {
+			// 文件末尾不完整的块头
+			break
+		}
+		block.Offset = int64(offset)
+		if block.Next() > int64(len(data)) {
+			return nil, ErrInvalidData
+		}
+		blocks = append(blocks, block)
+		offset = int(block.Next())
+	}
+
+	if len(blocks) == 0 && len(data) > 0 {
+		return nil, ErrNotSupported
+	}
+	return blocks, nil
+}
+
+// Decompress 解压一个块, 返回 Pages * PAGE_SIZE 字节
+func (b *Block) Decompress(data []byte) ([]byte, error) {
+	start := b.Offset + BLOCK_HEADER_LEN
+	if b.Next() > int64(len(data)) {
+		return nil, ErrInvalidData
+	}
+	payload := data[start:b.Next()]
+
+	if !b.IsCompressed() {
+		return payload, nil
+	}
+
+	output, err := xpress.DecompressWithSize(payload, b.Pages*PAGE_SIZE)
+	if err != nil {
+		return nil, err
+	}
+	if len(output) != b.Pages*PAGE_SIZE {
+		return nil, ErrInvalidData
+	}
+	return output, nil
+}
+
+// Pages 按文件中的顺序遍历所有块中的页, index为页在压缩页序列中的序号
+func Pages(data []byte, fn func(index int, page []byte) error) error {
+	blocks, err := FindBlocks(data)
+	if err != nil {
+		return err
+	}
+
+	index := 0
+	for _, block := range blocks {
+		pages, err := block.Decompress(data)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < block.Pages; i++ {
+			if err = fn(index, pages[i*PAGE_SIZE:(i+1)*PAGE_SIZE]); err != nil {
+				return err
+			}
+			index++
+		}
+	}
+	return nil
+}
+
+// MemoryRange 物理页号范围 [StartPage, EndPage), 与内存范围表(MEMORY_RANGE_ARRAY)中的项对应
+type MemoryRange struct {
+	StartPage uint64
+	EndPage   uint64
+}
+
+// ImageWithRanges 不使用文件中的内存范围表, 将压缩页序列按ranges依次放到对应的物理页上, 未覆盖的页为0
+func ImageWithRanges(data []byte, ranges []MemoryRange) ([]byte, error) {
+	var total, maxPage uint64
+	for _, r := range ranges {
+		if r.EndPage < r.StartPage {
+			return nil, ErrInvalidRange
+		}
+		total += r.EndPage - r.StartPage
+		if r.EndPage > maxPage {
+			maxPage = r.EndPage
+		}
+	}
+
+	image := make([]byte, maxPage*PAGE_SIZE)
+	placed := uint64(0)
+	rangeIndex := 0
+	pfn := uint64(0)
+	if len(ranges) > 0 {
+		pfn = ranges[0].StartPage
+	}
+
+	err := Pages(data, func(index int, page []byte) error {
+		for rangeIndex < len(ranges) && pfn >= ranges[rangeIndex].EndPage {
+			rangeIndex++
+			if rangeIndex < len(ranges) {
+				pfn = ranges[rangeIndex].StartPage
+			}
+		}
+		if rangeIndex >= len(ranges) {
+			return ErrInvalidRange
+		}
+		copy(image[pfn*PAGE_SIZE:], page)
+		pfn++
+		placed++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if placed != total {
+		return nil, ErrInvalidRange
+	}
+	return image, nil
+}
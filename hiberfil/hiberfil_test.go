@@ -0,0 +1,275 @@
+package hiberfil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/wabzsy/compression/xpress"
+)
+
+// blockHeader 构造Xpress块头, size为写入的数据大小(已按8字节对齐)
+func blockHeader(pages, size int) []byte {
+	header := make([]byte, BLOCK_HEADER_LEN)
+	copy(header, Magic)
+	binary.LittleEndian.PutUint32(header[8:], uint32(size-1)<<10|uint32(pages-1))
+	return header
+}
+
+func page(fill byte) []byte {
+	p := bytes.Repeat([]byte{fill}, PAGE_SIZE)
+	copy(p, []byte{'p', 'a', 'g', 'e', fill})
+	return p
+}
+
+// buildFile 文件头(一页) + 压缩的2页块 + 其他数据 + 未压缩的1页块
+func buildFile(t *testing.T) ([]byte, [][]byte) {
+	pages := [][]byte{page(1), page(2), page(3)}
+
+	compressed, err := xpress.Compress(append(append([]byte(nil), pages[0]...), pages[1]...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aligned := (len(compressed) + 7) &^ 7
+
+	file := make([]byte, PAGE_SIZE)
+	copy(file, "hibr")
+	file = append(file, blockHeader(2, aligned)...)
+	file = append(file, compressed...)
+	file = append(file, make([]byte, aligned-len(compressed))...)
+	// 块之间的其他数据
+	file = append(file, bytes.Repeat([]byte{0xCC}, 100)...)
+	file = append(file, blockHeader(1, PAGE_SIZE)...)
+	file = append(file, pages[2]...)
+	return file, pages
+}
+
+func TestFindBlocks(t *testing.T) {
+	file, _ := buildFile(t)
+	blocks, err := FindBlocks(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 {
+		t.Fatalf("blocks = %d, expected 2", len(blocks))
+	}
+	if blocks[0].Offset != PAGE_SIZE || blocks[0].Pages != 2 || !blocks[0].IsCompressed() {
+		t.Errorf("block 0 = %+v", blocks[0])
+	}
+	if blocks[1].Pages != 1 || blocks[1].IsCompressed() || blocks[1].Next() != int64(len(file)) {
+		t.Errorf("block 1 = %+v", blocks[1])
+	}
+}
+
+func TestPages(t *testing.T) {
+	file, pages := buildFile(t)
+	count := 0
+	err := Pages(file, func(index int, p []byte) error {
+		if index != count || !bytes.Equal(p, pages[index]) {
+			t.Errorf("page %d does not match", index)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != len(pages) {
+		t.Fatalf("pages = %d, expected %d", count, len(pages))
+	}
+}
+
+func TestImageWithRanges(t *testing.T) {
+	file, pages := buildFile(t)
+	image, err := ImageWithRanges(file, []MemoryRange{{StartPage: 0, EndPage: 2}, {StartPage: 5, EndPage: 6}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(image) != 6*PAGE_SIZE {
+		t.Fatalf("image size = %#x, expected %#x", len(image), 6*PAGE_SIZE)
+	}
+	for pfn, expected := range map[int][]byte{0: pages[0], 1: pages[1], 3: make([]byte, PAGE_SIZE), 5: pages[2]} {
+		if !bytes.Equal(image[pfn*PAGE_SIZE:(pfn+1)*PAGE_SIZE], expected) {
+			t.Errorf("page %d does not match", pfn)
+		}
+	}
+
+	for _, ranges := range [][]MemoryRange{
+		{{StartPage: 0, EndPage: 2}},
+		{{StartPage: 0, EndPage: 4}},
+		{{StartPage: 3, EndPage: 1}},
+	} {
+		if _, err = ImageWithRanges(file, ranges); err != ErrInvalidRange {
+			t.Errorf("ranges %v: err = %v, expected ErrInvalidRange", ranges, err)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	file, _ := buildFile(t)
+
+	// 最后一个块超出文件结尾
+	if _, err := FindBlocks(file[:len(file)-1]); err != ErrInvalidData {
+		t.Errorf("truncated block: err = %v, expected ErrInvalidData", err)
+	}
+
+	// 压缩数据损坏
+	broken := append([]byte(nil), file...)
+	for i := PAGE_SIZE + BLOCK_HEADER_LEN; i < PAGE_SIZE+BLOCK_HEADER_LEN+16; i++ {
+		broken[i] = 0xFF
+	}
+	if err := Pages(broken, func(int, []byte) error { return nil }); err == nil {
+		t.Error("corrupted block: expected an error")
+	}
+
+	// 没有Xpress块(Windows 8及之后的格式)
+	newer := make([]byte, 4*PAGE_SIZE)
+	copy(newer, "HIBR")
+	if _, err := FindBlocks(newer); err != ErrNotSupported {
+		t.Errorf("newer format: err = %v, expected ErrNotSupported", err)
+	}
+
+	if _, err := ParseBlockHeader(file[:BLOCK_HEADER_LEN]); err != ErrInvalidHeader {
+		t.Errorf("header: err = %v, expected ErrInvalidHeader", err)
+	}
+}
+
+// rangeTable 构造一页内存范围表
+func rangeTable(layout Layout, next uint64, ranges []MemoryRange) []byte {
+	put := func(b []byte, v uint64) {
+		if layout.PfnSize == 8 {
+			binary.LittleEndian.PutUint64(b, v)
+		} else {
+			binary.LittleEndian.PutUint32(b, uint32(v))
+		}
+	}
+	table := make([]byte, PAGE_SIZE)
+	put(table[layout.PfnSize:], next)
+	binary.LittleEndian.PutUint32(table[2*layout.PfnSize+4:], uint32(len(ranges)))
+	for i, r := range ranges {
+		entry := table[(i+1)*layout.EntrySize():]
+		put(entry[layout.PfnSize:], r.StartPage)
+		put(entry[2*layout.PfnSize:], r.EndPage)
+	}
+	return table
+}
+
+func alignPage(file []byte) []byte {
+	return append(file, make([]byte, (PAGE_SIZE-len(file)%PAGE_SIZE)%PAGE_SIZE)...)
+}
+
+// buildTableFile 文件头 + 表1(pfn 0~1, 4) + 压缩的2页块 + 未压缩的1页块 + 表2(pfn 7) + 压缩的1页块
+func buildTableFile(t *testing.T, layout Layout) ([]byte, map[uint64][]byte) {
+	pages := map[uint64][]byte{0: page(1), 1: page(2), 4: page(3), 7: page(4)}
+
+	first, err := xpress.Compress(append(append([]byte(nil), pages[0]...), pages[1]...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := xpress.Compress(pages[7])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := make([]byte, PAGE_SIZE)
+	copy(file, "hibr")
+	if layout.PfnSize == 8 {
+		binary.LittleEndian.PutUint64(file[layout.FirstTablePage:], 1)
+	} else {
+		binary.LittleEndian.PutUint32(file[layout.FirstTablePage:], 1)
+	}
+	file = append(file, rangeTable(layout, 0, []MemoryRange{{StartPage: 0, EndPage: 2}, {StartPage: 4, EndPage: 5}})...)
+	file = append(file, blockHeader(2, (len(first)+7)&^7)...)
+	file = append(file, first...)
+	file = append(file, make([]byte, (8-len(first)%8)%8)...)
+	file = append(file, blockHeader(1, PAGE_SIZE)...)
+	file = append(file, pages[4]...)
+
+	file = alignPage(file)
+	copy(file[PAGE_SIZE:], rangeTable(layout, uint64(len(file)/PAGE_SIZE), []MemoryRange{{StartPage: 0, EndPage: 2}, {StartPage: 4, EndPage: 5}}))
+	file = append(file, rangeTable(layout, 0, []MemoryRange{{StartPage: 7, EndPage: 8}})...)
+	file = append(file, blockHeader(1, (len(second)+7)&^7)...)
+	file = append(file, second...)
+	file = append(file, make([]byte, (8-len(second)%8)%8)...)
+	return file, pages
+}
+
+func TestReadRangeTables(t *testing.T) {
+	for _, layout := range []Layout{LayoutX86, LayoutX64} {
+		file, _ := buildTableFile(t, layout)
+		tables, err := ReadRangeTables(file, layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tables) != 2 || tables[0].Page != 1 || tables[0].NextTable != tables[1].Page || tables[1].NextTable != 0 {
+			t.Fatalf("pfn size %d: tables = %+v", layout.PfnSize, tables)
+		}
+		if tables[0].Pages() != 3 || len(tables[1].Ranges) != 1 || tables[1].Ranges[0] != (MemoryRange{StartPage: 7, EndPage: 8}) {
+			t.Fatalf("pfn size %d: ranges = %+v, %+v", layout.PfnSize, tables[0].Ranges, tables[1].Ranges)
+		}
+	}
+	if LayoutX86.MaxEntries() != 0xFF || LayoutX64.MaxEntries() != 0x7F {
+		t.Errorf("max entries = %#x, %#x", LayoutX86.MaxEntries(), LayoutX64.MaxEntries())
+	}
+}
+
+func TestImage(t *testing.T) {
+	for _, layout := range []Layout{LayoutX86, LayoutX64} {
+		file, pages := buildTableFile(t, layout)
+		image, err := Image(file, layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(image) != 8*PAGE_SIZE {
+			t.Fatalf("pfn size %d: image size = %#x, expected %#x", layout.PfnSize, len(image), 8*PAGE_SIZE)
+		}
+		for pfn := uint64(0); pfn < 8; pfn++ {
+			expected, ok := pages[pfn]
+			if !ok {
+				expected = make([]byte, PAGE_SIZE)
+			}
+			if !bytes.Equal(image[pfn*PAGE_SIZE:(pfn+1)*PAGE_SIZE], expected) {
+				t.Errorf("pfn size %d: page %d does not match", layout.PfnSize, pfn)
+			}
+		}
+	}
+}
+
+func TestRangeTableInvalid(t *testing.T) {
+	layout := LayoutX86
+	file, _ := buildTableFile(t, layout)
+	second := binary.LittleEndian.Uint32(file[PAGE_SIZE+4:])
+
+	for name, patch := range map[string]func([]byte){
+		"first table beyond file": func(b []byte) { binary.LittleEndian.PutUint32(b[layout.FirstTablePage:], 0x1000) },
+		"no table":                func(b []byte) { binary.LittleEndian.PutUint32(b[layout.FirstTablePage:], 0) },
+		"entry count":             func(b []byte) { binary.LittleEndian.PutUint32(b[PAGE_SIZE+12:], 0x100) },
+		"backward next table":     func(b []byte) { binary.LittleEndian.PutUint32(b[int(second)*PAGE_SIZE+4:], 1) },
+		"reversed range":          func(b []byte) { binary.LittleEndian.PutUint32(b[PAGE_SIZE+0x28:], 1) },
+	} {
+		broken := append([]byte(nil), file...)
+		patch(broken)
+		if _, err := Image(broken, layout); err != ErrInvalidTable {
+			t.Errorf("%s: err = %v, expected ErrInvalidTable", name, err)
+		}
+	}
+
+	// 超出物理地址范围的页号
+	file64, _ := buildTableFile(t, LayoutX64)
+	binary.LittleEndian.PutUint64(file64[PAGE_SIZE+0x30:], MAX_PAGE+1)
+	if _, err := Image(file64, LayoutX64); err != ErrInvalidTable {
+		t.Errorf("huge range: err = %v, expected ErrInvalidTable", err)
+	}
+
+	// 表中的页数与块中的页数不一致
+	for name, ranges := range map[string][]MemoryRange{
+		"more pages":  {{StartPage: 0, EndPage: 2}, {StartPage: 4, EndPage: 6}},
+		"fewer pages": {{StartPage: 0, EndPage: 2}},
+	} {
+		broken := append([]byte(nil), file...)
+		copy(broken[PAGE_SIZE:], rangeTable(layout, uint64(second), ranges))
+		if _, err := Image(broken, layout); err != ErrInvalidRange {
+			t.Errorf("%s: err = %v, expected ErrInvalidRange", name, err)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package hiberfil
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// 内存范围表(PO_MEMORY_RANGE_ARRAY)占一页, 由相同大小的项组成, 第一项是链接, 之后是范围:
+//   LINK:  Next(指针), NextTable(PFN), CheckSum(LE32), EntryCount(LE32)
+//   RANGE: PageNo(PFN), StartPage(PFN), EndPage(PFN), CheckSum(LE32)
+// PFN和指针在x86上是LE32, 在x64上是LE64, 项的大小为RANGE的大小(0x10 / 0x20), 所以每个表最多有0xFF / 0x7F个范围.
+// 第一个表的页号保存在文件头(PO_MEMORY_IMAGE)的FirstTablePage中, 每个表之后是它的各个范围中的页所在的Xpress块,
+// NextTable不为0时指向下一个表所在的页
+
+const (
+	// MAX_PAGE 52位物理地址对应的页号上限
+	MAX_PAGE = 1 << 40
+)
+
+var (
+	ErrInvalidTable = fmt.Errorf("the memory range table is invalid")
+)
+
+// Layout 文件头和内存范围表的布局
+type Layout struct {
+	// PfnSize PFN_NUMBER和指针的大小: 4或8
+	PfnSize int
+	// FirstTablePage 文件头中FirstTablePage的位置
+	FirstTablePage int
+}
+
+var (
+	// Windows XP / 2003 x86的PO_MEMORY_IMAGE
+	LayoutX86 = Layout{PfnSize: 4, FirstTablePage: 0x58}
+	// Windows XP / 2003 x64的PO_MEMORY_IMAGE, Vista / 7的文件头中还有其他字段, 需要调用方给出FirstTablePage的位置
+	LayoutX64 = Layout{PfnSize: 8, FirstTablePage: 0x68}
+)
+
+func (l Layout) pfn(data []byte) uint64 {
+	if l.PfnSize == 8 {
+		return binary.LittleEndian.Uint64(data)
+	}
+	return uint64(binary.LittleEndian.Uint32(data))
+}
+
+// EntrySize 表中每一项的大小
+func (l Layout) EntrySize() int {
+	return 4 * l.PfnSize
+}
+
+// MaxEntries 一个表中最多的范围数
+func (l Layout) MaxEntries() int {
+	return PAGE_SIZE/l.EntrySize() - 1
+}
+
+type RangeTable struct {
+	// Page 表所在的页号
+	Page uint64
+	// NextTable 下一个表所在的页号, 为0时是最后一个表
+	NextTable uint64
+	Ranges    []MemoryRange
+}
+
+// Pages 表中各个范围的总页数
+func (t *RangeTable) Pages() uint64 {
+	total := uint64(0)
+	for _, r := range t.Ranges {
+		total += r.EndPage - r.StartPage
+	}
+	return total
+}
+
+func ParseRangeTable(page []byte, layout Layout) (*RangeTable, error) {
+	if layout.PfnSize != 4 && layout.PfnSize != 8 {
+		return nil, ErrInvalidTable
+	}
+	if len(page) < PAGE_SIZE {
+		return nil, ErrInvalidTable
+	}
+
+	size := layout.EntrySize()
+	table := &RangeTable{
+		NextTable: layout.pfn(page[layout.PfnSize:]),
+	}
+
+	count := int(binary.LittleEndian.Uint32(page[2*layout.PfnSize+4:]))
+	if count > layout.MaxEntries() {
+		return nil, ErrInvalidTable
+	}
+
+	for i := 1; i <= count; i++ {
+		entry := page[i*size:]
+		r := MemoryRange{
+			StartPage: layout.pfn(entry[layout.PfnSize:]),
+			EndPage:   layout.pfn(entry[2*layout.PfnSize:]),
+		}
+		if r.EndPage < r.StartPage || r.EndPage > MAX_PAGE {
+			return nil, ErrInvalidTable
+		}
+		table.Ranges = append(table.Ranges, r)
+	}
+
+	return table, nil
+}
+
+// ReadRangeTables 从文件头中的FirstTablePage开始, 沿NextTable读取所有的内存范围表
+func ReadRangeTables(data []byte, layout Layout) ([]*RangeTable, error) {
+	if layout.FirstTablePage < 0 || len(data) < layout.FirstTablePage+layout.PfnSize {
+		return nil, ErrInvalidTable
+	}
+
+	var tables []*RangeTable
+	page := layout.pfn(data[layout.FirstTablePage:])
+	for page != 0 {
+		// 表按顺序写入, 页号必须递增
+		if page >= uint64(len(data)/PAGE_SIZE) || (len(tables) > 0 && page <= tables[len(tables)-1].Page) {
+			return nil, ErrInvalidTable
+		}
+
+		table, err := ParseRangeTable(data[page*PAGE_SIZE:], layout)
+		if err != nil {
+			return nil, err
+		}
+		table.Page = page
+		tables = append(tables, table)
+		page = table.NextTable
+	}
+
+	if len(tables) == 0 {
+		return nil, ErrInvalidTable
+	}
+	return tables, nil
+}
+
+// PhysicalPages 按内存范围表遍历所有的页, pfn为页的物理页号.
+// 每个表的页从表之后的第一个Xpress块开始, 到下一个表之前结束, 块中的页数必须与表中的范围一致
+func PhysicalPages(data []byte, layout Layout, fn func(pfn uint64, page []byte) error) error {
+	blocks, err := FindBlocks(data)
+	if err != nil {
+		return err
+	}
+	tables, err := ReadRangeTables(data, layout)
+	if err != nil {
+		return err
+	}
+
+	next := 0
+	for i, table := range tables {
+		start := int64(table.Page) * PAGE_SIZE
+		end := int64(len(data))
+		if i+1 < len(tables) {
+			end = int64(tables[i+1].Page) * PAGE_SIZE
+		}
+		for next < len(blocks) && blocks[next].Offset < start {
+			next++
+		}
+
+		// pages 当前块中还没有使用的页
+		var pages []byte
+		for _, r := range table.Ranges {
+			for pfn := r.StartPage; pfn < r.EndPage; pfn++ {
+				if len(pages) == 0 {
+					if next >= len(blocks) || blocks[next].Next() > end {
+						return ErrInvalidRange
+					}
+					if pages, err = blocks[next].Decompress(data); err != nil {
+						return err
+					}
+					next++
+				}
+				if err = fn(pfn, pages[:PAGE_SIZE]); err != nil {
+					return err
+				}
+				pages = pages[PAGE_SIZE:]
+			}
+		}
+		// 表之后还有没用到的页或块
+		if len(pages) != 0 || (next < len(blocks) && blocks[next].Next() <= end) {
+			return ErrInvalidRange
+		}
+	}
+	return nil
+}
+
+// Image 按文件中的内存范围表生成平坦的物理内存镜像, 未覆盖的页为0.
+// 镜像的大小由最大的物理页号决定, 物理地址空间中有很大的空洞时使用PhysicalPages
+func Image(data []byte, layout Layout) ([]byte, error) {
+	tables, err := ReadRangeTables(data, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	maxPage := uint64(0)
+	for _, table := range tables {
+		for _, r := range table.Ranges {
+			if r.EndPage > maxPage {
+				maxPage = r.EndPage
+			}
+		}
+	}
+
+	image := make([]byte, maxPage*PAGE_SIZE)
+	err = PhysicalPages(data, layout, func(pfn uint64, page []byte) error {
+		copy(image[pfn*PAGE_SIZE:], page)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return image, nil
+}
@@ -0,0 +1,107 @@
+package lznt1
+
+import "encoding/binary"
+
+// 数据流由一系列块组成, 每个块以2字节的块头开始:
+//   0~11位: 块数据的长度 - 1, 12~14位: 大小位, 15位: 是否压缩
+// 块头为0x0000或剩余不足2字节时数据流结束, 之后的数据被忽略.
+// 除最后一个块外, 每个块在输出中占用大小位表示的大小, 解压后不足的部分以0填充;
+// 解压后超过该大小时, 严格模式返回ErrInvalidChunkSize, 否则按CHUNK_SIZE处理(Windows忽略大小位)
+
+type chunkInfo struct {
+	header uint16
+	// offset 块数据(块头之后)在输入中的位置
+	offset int
+	length int
+	// start 块在解压后数据中的位置, size 块在输出中占用的大小(最后一个块为解压后的实际大小)
+	start int64
+	size  int
+	// data 建立索引时已经解压的数据
+	data []byte
+}
+
+func (c *chunkInfo) compressed() bool {
+	return c.header&0x8000 != 0
+}
+
+func (c *chunkInfo) decompress(input []byte) ([]byte, error) {
+	raw := input[c.offset : c.offset+c.length]
+	if !c.compressed() {
+		return raw, nil
+	}
+	return NewDecompressor(raw).DecompressChunk(c.length)
+}
+
+// chunkSize 块在输出中占用的大小, length为块解压后的大小
+func chunkSize(header uint16, length int, strict bool) (int, error) {
+	size := HeaderChunkSize(header)
+	if length > size {
+		if strict || length > CHUNK_SIZE {
+			return 0, ErrInvalidChunkSize
+		}
+		size = CHUNK_SIZE
+	}
+	return size, nil
+}
+
+// parseChunks 建立块索引, 输出达到limit(大于0时)后忽略剩余的块.
+// decompressAll为false时只解压确定大小所需要的块: 最后一个块, 大小位小于CHUNK_SIZE的压缩块, 以及严格模式下的所有块
+func parseChunks(input []byte, strict, decompressAll bool, limit int64) ([]chunkInfo, error) {
+	var chunks []chunkInfo
+
+	start := int64(0)
+	for cursor := 0; cursor+2 <= len(input) && (limit <= 0 || start < limit); {
+		header := binary.LittleEndian.Uint16(input[cursor:])
+		if header == 0 {
+			break
+		}
+
+		// Flags:
+		//   Highest bit (0x8) means compressed
+		// The other bits are always 011 (0x3) and have unknown meaning:
+		//   The last two bits are possibly uncompressed chunk size (512, 1024, 2048, or 4096)
+		//   However in NT 3.51, NT 4 SP1, XP SP2, Win 7 SP1 the actual chunk size is always 4096
+		//   and the unknown flags are always 011 (0x3)
+		// 这里按大小位处理, 每个块可以有不同的大小
+		info := chunkInfo{
+			header: header,
+			offset: cursor + 2,
+			length: int(header&0x0FFF) + 1,
+			start:  start,
+		}
+		if info.offset+info.length > len(input) {
+			return nil, ErrInvalidData
+		}
+		cursor = info.offset + info.length
+
+		last := cursor+2 > len(input) || binary.LittleEndian.Uint16(input[cursor:]) == 0
+		length := info.length
+		if info.compressed() {
+			// 大小位为CHUNK_SIZE的压缩块不需要解压就能确定大小, 解压后超过CHUNK_SIZE的在读取时报错
+			length = CHUNK_SIZE
+		}
+		if decompressAll || last || strict || (info.compressed() && HeaderChunkSize(header) < CHUNK_SIZE) {
+			data, err := info.decompress(input)
+			if err != nil {
+				return nil, err
+			}
+			info.data, length = data, len(data)
+		}
+
+		size, err := chunkSize(header, length, strict)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			size = length
+		} else if length < size && strict {
+			return nil, ErrInvalidChunkSize
+		}
+		info.size = size
+
+		chunks = append(chunks, info)
+		start += int64(size)
+	}
+
+	return chunks, nil
+}
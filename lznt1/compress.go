@@ -6,7 +6,8 @@ import (
 )
 
 const (
-	CHUNK_SIZE = 0x1000
+	CHUNK_SIZE     = 0x1000
+	MIN_CHUNK_SIZE = 0x200
 )
 
 type Entry struct {
@@ -89,6 +90,7 @@ func NewDictionary() *Dictionary {
 
 type Compressor struct {
 	dict           *Dictionary
+	chunkSize      int
 	__input        []byte
 	__inputCursor  int
 	__output       []byte
@@ -160,25 +162,36 @@ func (c *Compressor) compressChunk(chunkLength int) int {
 }
 
 func (c *Compressor) MaxCompressedSize() int {
-	return len(c.__input) + 3 + 2*((len(c.__input)+CHUNK_SIZE-1)/CHUNK_SIZE)
+	return len(c.__input) + 3 + 2*((len(c.__input)+c.chunkSize-1)/c.chunkSize)
 }
 
 func (c *Compressor) Compress() ([]byte, error) {
+	sizeBits := ChunkSizeBits(c.chunkSize)
+	if sizeBits < 0 {
+		return nil, ErrInvalidChunkSize
+	}
+
 	c.__output = make([]byte, c.MaxCompressedSize())
 
 	for c.__inputCursor < len(c.__input) {
 		// Compress the next chunk
-		chunkLength := Min(len(c.__input)-c.__inputCursor, CHUNK_SIZE)
+		chunkLength := Min(len(c.__input)-c.__inputCursor, c.chunkSize)
 		compressedLength := c.compressChunk(chunkLength)
 
 		flags := 0
 		if compressedLength < chunkLength {
 			// 压缩成功, 数据已在compressChunk里写入了
-			flags = 0xB000
+			flags = 0x8000 | sizeBits<<12
+		} else if chunkLength == 1 && sizeBits == 0 {
+			// 大小位为0时1字节的未压缩块的块头为0x0000(结束标志), 改为只有一个literal的压缩块
+			compressedLength = 2
+			flags = 0x8000
+			c.__output[c.__outputCursor+2] = 0
+			c.__output[c.__outputCursor+3] = c.__input[c.__inputCursor]
 		} else {
 			// chunk is uncompressed
 			compressedLength = chunkLength
-			flags = 0x3000
+			flags = sizeBits << 12
 			// 跳过header的位置 先写后面的数据, header在下面统一写
 			copy(c.__output[c.__outputCursor+2:], c.__input[c.__inputCursor:c.__inputCursor+compressedLength])
 		}
@@ -195,9 +208,18 @@ func (c *Compressor) Compress() ([]byte, error) {
 }
 
 func NewCompressor(input []byte) *Compressor {
+	return NewCompressorWithOptions(input, Options{})
+}
+
+func NewCompressorWithOptions(input []byte, options Options) *Compressor {
+	chunkSize := options.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = CHUNK_SIZE
+	}
 	return &Compressor{
-		dict:    NewDictionary(),
-		__input: input,
+		dict:      NewDictionary(),
+		chunkSize: chunkSize,
+		__input:   input,
 	}
 }
 
@@ -3,13 +3,13 @@ package lznt1
 import (
 	"bytes"
 	"encoding/binary"
-	"errors"
-	"fmt"
 )
 
 type Decompressor struct {
 	__input       []byte
 	__inputCursor int
+
+	strict bool
 }
 
 func (d *Decompressor) DecompressChunk(chunkLength int) ([]byte, error) {
@@ -51,7 +51,7 @@ func (d *Decompressor) DecompressChunk(chunkLength int) ([]byte, error) {
 				index := decompressed.Len() - int(offset)
 
 				if index < 0 {
-					return nil, errors.New("invalid chunk")
+					return nil, ErrInvalidData
 				}
 
 				if length >= offset {
@@ -70,46 +70,47 @@ func (d *Decompressor) DecompressChunk(chunkLength int) ([]byte, error) {
 }
 
 func (d *Decompressor) Decompress() ([]byte, error) {
-	result := &bytes.Buffer{}
-
-	for d.__inputCursor < len(d.__input) {
-		// Read chunk header
-		header := binary.LittleEndian.Uint16(d.__input[d.__inputCursor : d.__inputCursor+2])
-		if header == 0 {
-			return nil, fmt.Errorf("the compressed data is wrong")
-		}
-
-		chunkLength := int((header & 0x0FFF) + 1)
-
-		if chunkLength >= len(d.__input) {
-			return nil, fmt.Errorf("the compressed data is wrong")
-		}
+	chunks, err := parseChunks(d.__input, d.strict, true, 0)
+	if err != nil {
+		return nil, err
+	}
 
-		d.__inputCursor += 2
+	result := &bytes.Buffer{}
+	for _, info := range chunks {
+		result.Write(info.data)
+		result.Write(make([]byte, info.size-len(info.data)))
+	}
+	return result.Bytes(), nil
+}
 
-		// Flags:
-		//   Highest bit (0x8) means compressed
-		// The other bits are always 011 (0x3) and have unknown meaning:
-		//   The last two bits are possibly uncompressed chunk size (512, 1024, 2048, or 4096)
-		//   However in NT 3.51, NT 4 SP1, XP SP2, Win 7 SP1 the actual chunk size is always 4096
-		//   and the unknown flags are always 011 (0x3)
+// DecompressWithSize 输出达到outputSize后忽略剩余的块, 结果以0填充到outputSize(NTFS的压缩单元),
+// 块中的数据超出outputSize时返回ErrInvalidData
+func (d *Decompressor) DecompressWithSize(outputSize int) ([]byte, error) {
+	if outputSize < 0 {
+		return nil, ErrInvalidData
+	}
+	chunks, err := parseChunks(d.__input, d.strict, true, int64(outputSize))
+	if err != nil {
+		return nil, err
+	}
 
-		if (header & 0x8000) != 0 {
-			if decompressed, err := d.DecompressChunk(chunkLength); err == nil {
-				result.Write(decompressed)
-			} else {
-				return nil, err
-			}
-		} else {
-			result.Write(d.__input[d.__inputCursor : d.__inputCursor+chunkLength])
+	output := make([]byte, outputSize)
+	for _, info := range chunks {
+		if info.start+int64(len(info.data)) > int64(outputSize) {
+			return nil, ErrInvalidData
 		}
-
-		d.__inputCursor += chunkLength
+		copy(output[info.start:], info.data)
 	}
-
-	return result.Bytes(), nil
+	return output, nil
 }
 
 func NewDecompressor(input []byte) *Decompressor {
-	return &Decompressor{__input: input}
+	return NewDecompressorWithOptions(input, Options{})
+}
+
+func NewDecompressorWithOptions(input []byte, options Options) *Decompressor {
+	return &Decompressor{
+		__input: input,
+		strict:  options.Strict,
+	}
 }
@@ -1,9 +1,81 @@
 package lznt1
 
+import (
+	"fmt"
+	"io"
+)
+
+var (
+	ErrInvalidData      = fmt.Errorf("the input data is invalid")
+	ErrInvalidChunkSize = fmt.Errorf("the LZNT1 chunk size is invalid")
+)
+
+// Options
+// 块头的第12~13位表示块的未压缩大小: 512 << bits (0: 512, 1: 1024, 2: 2048, 3: 4096),
+// RtlCompressBuffer生成的数据总是4096
+type Options struct {
+	// ChunkSize 压缩时每个块的未压缩大小(512/1024/2048/4096), 0表示CHUNK_SIZE
+	ChunkSize int
+	// Strict 解压时严格校验块头的大小位: 块解压后不能超过该大小, 最后一个块以外的块必须正好等于该大小.
+	// 非严格模式下不足的部分以0填充(与RtlDecompressBuffer相同), 超过该大小(但不超过4096)时按4096处理
+	Strict bool
+}
+
+// ChunkSizeBits 返回块大小对应的块头大小位, 不合法时返回-1
+func ChunkSizeBits(chunkSize int) int {
+	for bits := 0; bits < 4; bits++ {
+		if MIN_CHUNK_SIZE<<bits == chunkSize {
+			return bits
+		}
+	}
+	return -1
+}
+
+// HeaderChunkSize 返回块头中大小位表示的块大小
+func HeaderChunkSize(header uint16) int {
+	return MIN_CHUNK_SIZE << ((header >> 12) & 3)
+}
+
+func CompressWithOptions(input []byte, options Options) ([]byte, error) {
+	return NewCompressorWithOptions(input, options).Compress()
+}
+
 func Compress(input []byte) ([]byte, error) {
 	return NewCompressor(input).Compress()
 }
 
+func DecompressWithOptions(input []byte, options Options) ([]byte, error) {
+	return NewDecompressorWithOptions(input, options).Decompress()
+}
+
 func Decompress(input []byte) ([]byte, error) {
 	return NewDecompressor(input).Decompress()
 }
+
+func DecompressWithSize(input []byte, outputSize int) ([]byte, error) {
+	return NewDecompressor(input).DecompressWithSize(outputSize)
+}
+
+// DecompressRange 只解压 [offset, offset+length) 涉及到的块, 超出结尾的部分被截断
+func DecompressRange(input []byte, offset, length int) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, ErrInvalidData
+	}
+	reader, err := NewReaderAt(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(offset) >= reader.Size() {
+		return []byte{}, nil
+	}
+	if remain := reader.Size() - int64(offset); int64(length) > remain {
+		length = int(remain)
+	}
+
+	output := make([]byte, length)
+	if _, err = reader.ReadAt(output, int64(offset)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return output, nil
+}
@@ -0,0 +1,315 @@
+package lznt1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// testData 前半部分可压缩, 后半部分为伪随机数据, 使压缩和未压缩的块都会出现
+func testData(size int) []byte {
+	data := make([]byte, size)
+	seed := uint32(1)
+	for i := range data {
+		if i%1500 < 700 {
+			data[i] = "lznt1 chunk test "[i%17]
+		} else {
+			seed = seed*1103515245 + 12345
+			data[i] = byte(seed >> 16)
+		}
+	}
+	return data
+}
+
+func TestRoundTripChunkSizes(t *testing.T) {
+	for bits := 0; bits < 4; bits++ {
+		chunkSize := MIN_CHUNK_SIZE << bits
+		for _, chunks := range []int{0, 1, 2, 3} {
+			for _, delta := range []int{-2, -1, 0, 1, 2} {
+				size := chunks*chunkSize + delta
+				if size < 0 {
+					continue
+				}
+				input := testData(size)
+				options := Options{ChunkSize: chunkSize, Strict: true}
+
+				compressed, err := CompressWithOptions(input, options)
+				if err != nil {
+					t.Fatalf("chunk size %d, length %d: %v", chunkSize, size, err)
+				}
+				for cursor := 0; cursor+2 <= len(compressed); {
+					header := binary.LittleEndian.Uint16(compressed[cursor:])
+					if header == 0 {
+						t.Fatalf("chunk size %d, length %d: zero chunk header at %d", chunkSize, size, cursor)
+					}
+					if ChunkSizeBits(HeaderChunkSize(header)) != bits {
+						t.Fatalf("chunk size %d, length %d: header %#04x has wrong size bits", chunkSize, size, header)
+					}
+					cursor += 2 + int(header&0x0FFF) + 1
+				}
+
+				output, err := DecompressWithOptions(compressed, options)
+				if err != nil {
+					t.Fatalf("chunk size %d, length %d: %v", chunkSize, size, err)
+				}
+				if !bytes.Equal(output, input) {
+					t.Fatalf("chunk size %d, length %d: round-trip mismatch", chunkSize, size)
+				}
+
+				reader, err := NewReaderAt(compressed)
+				if err != nil {
+					t.Fatalf("chunk size %d, length %d: %v", chunkSize, size, err)
+				}
+				if reader.Size() != int64(size) {
+					t.Fatalf("chunk size %d, length %d: reader size %d", chunkSize, size, reader.Size())
+				}
+			}
+		}
+	}
+}
+
+func TestDecompressInvalid(t *testing.T) {
+	for _, input := range [][]byte{
+		{0x05, 0xB0, 0x00},
+		// 第一个符号就是匹配
+		{0x02, 0xB0, 0x01, 0x00, 0x00},
+	} {
+		if _, err := Decompress(input); err != ErrInvalidData {
+			t.Errorf("Decompress(% x) = %v, expected ErrInvalidData", input, err)
+		}
+	}
+
+	if _, err := CompressWithOptions([]byte{1}, Options{ChunkSize: 1000}); err != ErrInvalidChunkSize {
+		t.Errorf("chunk size: err = %v, expected ErrInvalidChunkSize", err)
+	}
+}
+
+// uncompressedChunk 未压缩的块, bits为块头的大小位
+func uncompressedChunk(data []byte, bits int) []byte {
+	header := make([]byte, 2)
+	binary.LittleEndian.PutUint16(header, uint16(bits<<12|(len(data)-1)))
+	return append(header, data...)
+}
+
+func TestChunkSizes(t *testing.T) {
+	first := testData(1000)
+	second := []byte("second chunk")
+
+	// 大小位为512的压缩块, 解压后为1000字节
+	compressed, err := Compress(bytes.Repeat([]byte{'a'}, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.LittleEndian.PutUint16(compressed, binary.LittleEndian.Uint16(compressed)&^0x3000)
+
+	padded := append(append(append([]byte(nil), first...), make([]byte, CHUNK_SIZE-len(first))...), second...)
+	for _, test := range []struct {
+		name     string
+		input    []byte
+		expected []byte
+	}{
+		// 超过大小位的块按4096处理
+		{"oversize uncompressed", append(uncompressedChunk(first, 0), uncompressedChunk(second, 3)...), padded},
+		{"oversize compressed", append(compressed[:len(compressed):len(compressed)], uncompressedChunk(second, 3)...),
+			append(append(bytes.Repeat([]byte{'a'}, 1000), make([]byte, CHUNK_SIZE-1000)...), second...)},
+		// 结束标志之后的数据被忽略, 结束标志之前的块是最后一个块, 不填充
+		{"terminator", append(append(uncompressedChunk(first, 0), 0, 0), uncompressedChunk(second, 3)...), first},
+		{"terminator after chunks", append(append(uncompressedChunk(first, 0), uncompressedChunk(second, 3)...), 0, 0, 0xFF), padded},
+		{"single trailing byte", append(uncompressedChunk(first, 3), 0xFF), first},
+		{"empty", nil, []byte{}},
+		{"only terminator", []byte{0, 0}, []byte{}},
+	} {
+		output, err := Decompress(test.input)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !bytes.Equal(output, test.expected) {
+			t.Fatalf("%s: Decompress returned %d bytes, expected %d", test.name, len(output), len(test.expected))
+		}
+
+		reader, err := NewReaderAt(test.input)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if reader.Size() != int64(len(test.expected)) {
+			t.Fatalf("%s: reader size %d, expected %d", test.name, reader.Size(), len(test.expected))
+		}
+		for index := 0; index < reader.Chunks(); index++ {
+			if _, err = reader.DecompressChunk(index); err != nil {
+				t.Fatalf("%s: chunk %d: %v", test.name, index, err)
+			}
+		}
+
+		output, err = DecompressRange(test.input, 0, len(test.expected)+100)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !bytes.Equal(output, test.expected) {
+			t.Fatalf("%s: DecompressRange returned %d bytes, expected %d", test.name, len(output), len(test.expected))
+		}
+
+		output, err = DecompressWithSize(test.input, 2*CHUNK_SIZE)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		expected := append(append([]byte(nil), test.expected...), make([]byte, 2*CHUNK_SIZE-len(test.expected))...)
+		if !bytes.Equal(output, expected) {
+			t.Fatalf("%s: DecompressWithSize does not match", test.name)
+		}
+	}
+
+	// 严格模式不允许超过大小位, 也不允许最后一个块以外的块不足
+	for _, input := range [][]byte{
+		append(uncompressedChunk(first, 0), uncompressedChunk(second, 3)...),
+		append(uncompressedChunk(first, 3), uncompressedChunk(second, 3)...),
+	} {
+		if _, err = DecompressWithOptions(input, Options{Strict: true}); err != ErrInvalidChunkSize {
+			t.Errorf("strict: err = %v, expected ErrInvalidChunkSize", err)
+		}
+	}
+
+	// 输出达到outputSize后忽略剩余的块, 但块中的数据不能超出outputSize
+	input := append(uncompressedChunk(first, 3), uncompressedChunk(second, 3)...)
+	if output, err := DecompressWithSize(input, CHUNK_SIZE); err != nil || !bytes.Equal(output[:len(first)], first) {
+		t.Errorf("limited output: err = %v", err)
+	}
+	if _, err = DecompressWithSize(input, 500); err != ErrInvalidData {
+		t.Errorf("data beyond outputSize: err = %v, expected ErrInvalidData", err)
+	}
+}
+
+// randomAccessData 压缩的块, 不可压缩的块(原样存储), 压缩的块, 不完整的最后一个块
+func randomAccessData(t *testing.T) ([]byte, []byte) {
+	data := bytes.Repeat([]byte("random access "), (3*CHUNK_SIZE+777)/14+1)[:3*CHUNK_SIZE+777]
+	seed := uint32(1)
+	for i := CHUNK_SIZE; i < 2*CHUNK_SIZE; i++ {
+		seed = seed*1103515245 + 12345
+		data[i] = byte(seed >> 16)
+	}
+	compressed, err := Compress(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data, compressed
+}
+
+func TestReaderAt(t *testing.T) {
+	data, compressed := randomAccessData(t)
+	size := int64(len(data))
+
+	// 结束标志之后的数据被忽略
+	for _, input := range [][]byte{compressed, append(append(compressed[:len(compressed):len(compressed)], 0, 0), 0x12, 0x34)} {
+		reader, err := NewReaderAt(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reader.Size() != size || reader.Chunks() != 4 {
+			t.Fatalf("size = %d, chunks = %d", reader.Size(), reader.Chunks())
+		}
+		if reader.chunks[1].compressed() || !reader.chunks[0].compressed() {
+			t.Fatal("the second chunk should be stored uncompressed")
+		}
+
+		for index := 0; index < 4; index++ {
+			end := (index + 1) * CHUNK_SIZE
+			if end > len(data) {
+				end = len(data)
+			}
+			chunk, err := reader.DecompressChunk(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(chunk, data[index*CHUNK_SIZE:end]) {
+				t.Fatalf("chunk %d does not match", index)
+			}
+		}
+		for _, index := range []int{-1, 4} {
+			if _, err = reader.DecompressChunk(index); err != ErrInvalidData {
+				t.Errorf("chunk %d: err = %v, expected ErrInvalidData", index, err)
+			}
+		}
+
+		for _, r := range []struct {
+			name        string
+			offset, end int64
+		}{
+			{"inside a chunk", 100, 300},
+			{"inside the uncompressed chunk", CHUNK_SIZE + 10, 2*CHUNK_SIZE - 10},
+			{"across a chunk boundary", CHUNK_SIZE - 50, CHUNK_SIZE + 50},
+			{"across three chunks", CHUNK_SIZE - 1, 3*CHUNK_SIZE + 1},
+			{"the partial last chunk", 3 * CHUNK_SIZE, size},
+			{"everything", 0, size},
+		} {
+			part := make([]byte, r.end-r.offset)
+			n, err := reader.ReadAt(part, r.offset)
+			if err != nil || n != len(part) {
+				t.Fatalf("%s: n = %d, err = %v", r.name, n, err)
+			}
+			if !bytes.Equal(part, data[r.offset:r.end]) {
+				t.Fatalf("%s: ReadAt does not match", r.name)
+			}
+		}
+
+		// 超出结尾的读取
+		part := make([]byte, 100)
+		if n, err := reader.ReadAt(part, size-40); n != 40 || err != io.EOF || !bytes.Equal(part[:n], data[size-40:]) {
+			t.Errorf("past the end: n = %d, err = %v", n, err)
+		}
+		if n, err := reader.ReadAt(part, size); n != 0 || err != io.EOF {
+			t.Errorf("at the end: n = %d, err = %v", n, err)
+		}
+		if _, err := reader.ReadAt(part, -1); err != ErrInvalidData {
+			t.Errorf("negative offset: err = %v, expected ErrInvalidData", err)
+		}
+	}
+}
+
+func TestDecompressRange(t *testing.T) {
+	data, compressed := randomAccessData(t)
+	size := len(data)
+
+	for _, r := range []struct {
+		name                   string
+		offset, length, expect int
+	}{
+		{"inside a chunk", 10, 20, 20},
+		{"inside the uncompressed chunk", CHUNK_SIZE, CHUNK_SIZE, CHUNK_SIZE},
+		{"across a chunk boundary", 2*CHUNK_SIZE - 3, 6, 6},
+		{"truncated at the end", size - 5, 100, 5},
+		{"past the end", size + 10, 100, 0},
+		{"empty", 100, 0, 0},
+	} {
+		output, err := DecompressRange(compressed, r.offset, r.length)
+		if err != nil {
+			t.Fatalf("%s: %v", r.name, err)
+		}
+		if len(output) != r.expect {
+			t.Fatalf("%s: %d bytes, expected %d", r.name, len(output), r.expect)
+		}
+		if r.expect > 0 && !bytes.Equal(output, data[r.offset:r.offset+r.expect]) {
+			t.Fatalf("%s: data does not match", r.name)
+		}
+	}
+
+	// 最后一个块以外的块不足大小时, 读取到的填充部分为0
+	short := append(uncompressedChunk([]byte("short"), 3), uncompressedChunk([]byte("next"), 3)...)
+	output, err := DecompressRange(short, 3, CHUNK_SIZE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := append(append([]byte("rt"), make([]byte, CHUNK_SIZE-5)...), "nex"...)
+	if !bytes.Equal(output, expected) {
+		t.Fatal("padded chunk does not match")
+	}
+
+	for _, r := range [][2]int{{-1, 10}, {0, -1}} {
+		if _, err = DecompressRange(compressed, r[0], r[1]); err != ErrInvalidData {
+			t.Errorf("range %v: err = %v, expected ErrInvalidData", r, err)
+		}
+	}
+	// 块数据超出输入
+	if _, err = DecompressRange(compressed[:100], 0, 10); err != ErrInvalidData {
+		t.Errorf("truncated input: err = %v, expected ErrInvalidData", err)
+	}
+}
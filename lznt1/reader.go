@@ -0,0 +1,116 @@
+package lznt1
+
+import (
+	"io"
+	"sort"
+	"sync"
+)
+
+// ReaderAt 与RtlDecompressFragment相同的随机读取, 块在输出中的位置与Decompress相同, 读取时只解压涉及到的块
+type ReaderAt struct {
+	input  []byte
+	chunks []chunkInfo
+	size   int64
+
+	mutex       sync.Mutex
+	cachedChunk int
+	cached      []byte
+}
+
+func NewReaderAt(input []byte) (*ReaderAt, error) {
+	r := &ReaderAt{
+		input:       input,
+		cachedChunk: -1,
+	}
+
+	chunks, err := parseChunks(input, false, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	r.chunks = chunks
+	if n := len(chunks); n > 0 {
+		r.size = chunks[n-1].start + int64(chunks[n-1].size)
+	}
+
+	return r, nil
+}
+
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+func (r *ReaderAt) Chunks() int {
+	return len(r.chunks)
+}
+
+// DecompressChunk 解压第index个块, 返回该块实际的输出(不填充), 超过块在输出中占用的大小时返回ErrInvalidChunkSize
+func (r *ReaderAt) DecompressChunk(index int) ([]byte, error) {
+	if index < 0 || index >= len(r.chunks) {
+		return nil, ErrInvalidData
+	}
+	info := &r.chunks[index]
+	if info.data != nil {
+		return info.data, nil
+	}
+
+	output, err := info.decompress(r.input)
+	if err != nil {
+		return nil, err
+	}
+	if len(output) > info.size {
+		return nil, ErrInvalidChunkSize
+	}
+	return output, nil
+}
+
+func (r *ReaderAt) chunk(index int) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.cachedChunk == index {
+		return r.cached, nil
+	}
+	data, err := r.DecompressChunk(index)
+	if err != nil {
+		return nil, err
+	}
+	r.cachedChunk, r.cached = index, data
+	return data, nil
+}
+
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrInvalidData
+	}
+
+	n := 0
+	for n < len(p) {
+		position := off + int64(n)
+		if position >= r.size {
+			return n, io.EOF
+		}
+		index := sort.Search(len(r.chunks), func(i int) bool {
+			return r.chunks[i].start > position
+		}) - 1
+		data, err := r.chunk(index)
+		if err != nil {
+			return n, err
+		}
+
+		from := int(position - r.chunks[index].start)
+		m := r.chunks[index].size - from
+		if m > len(p)-n {
+			m = len(p) - n
+		}
+		// 不足块大小的部分为0
+		copied := 0
+		if from < len(data) {
+			copied = copy(p[n:n+m], data[from:])
+		}
+		for i := n + copied; i < n+m; i++ {
+			p[i] = 0
+		}
+		n += m
+	}
+	return n, nil
+}
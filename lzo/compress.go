@@ -0,0 +1,320 @@
+package lzo
+
+const (
+	D_BITS    = 14
+	HASH_BITS = 16
+)
+
+type Level struct {
+	// MaxChain 为0时使用LZO1X-1的单次哈希探测, 否则使用哈希链搜索
+	MaxChain   int
+	NiceLength int
+	Lazy       bool
+}
+
+var (
+	Level1   = Level{}
+	Level999 = Level{MaxChain: 4096, NiceLength: 2048, Lazy: true}
+)
+
+type Compressor struct {
+	level Level
+
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+
+	// literalStart 尚未输出的字面量起始位置
+	literalStart int
+	// state 与解压端一致: 0 匹配后无字面量, 1~3 匹配后的短字面量, 4 长字面量
+	state int
+
+	dict []int
+	head []int
+	prev []int
+}
+
+func (c *Compressor) putByte(b int) {
+	c.__output = append(c.__output, byte(b))
+}
+
+func (c *Compressor) putLength(length int) {
+	for length > 255 {
+		length -= 255
+		c.putByte(0)
+	}
+	c.putByte(length)
+}
+
+// flushLiterals 输出 [literalStart, end) 之间的字面量
+func (c *Compressor) flushLiterals(end int) {
+	t := end - c.literalStart
+	if t == 0 {
+		return
+	}
+
+	if len(c.__output) == 0 && t <= 238 {
+		c.putByte(17 + t)
+	} else if t <= 3 {
+		// 附加在上一个匹配的距离字段的低2位
+		c.__output[len(c.__output)-2] |= byte(t)
+	} else if t <= 18 {
+		c.putByte(t - 3)
+	} else {
+		c.putByte(0)
+		c.putLength(t - 18)
+	}
+
+	c.__output = append(c.__output, c.__input[c.literalStart:end]...)
+	c.literalStart = end
+
+	if t <= 3 {
+		c.state = t
+	} else {
+		c.state = 4
+	}
+}
+
+// matchCost 返回匹配编码后的字节数, 返回0表示当前状态下无法编码
+func (c *Compressor) matchCost(state, offset, length int) int {
+	switch {
+	case length == 2:
+		if state >= 1 && state <= 3 && offset <= M1_MAX_OFFSET {
+			return 2
+		}
+		return 0
+	case length == 3 && state == 4 && offset > M2_MAX_OFFSET && offset <= M2_MAX_OFFSET+M1_MAX_OFFSET:
+		return 2
+	case length <= M2_MAX_LEN && offset <= M2_MAX_OFFSET:
+		return 2
+	case offset <= M3_MAX_OFFSET:
+		if length <= M3_MAX_LEN {
+			return 3
+		}
+		return 4 + (length-M3_MAX_LEN-1)/255
+	case offset <= M4_MAX_OFFSET:
+		if length <= M4_MAX_LEN {
+			return 3
+		}
+		return 4 + (length-M4_MAX_LEN-1)/255
+	}
+	return 0
+}
+
+func (c *Compressor) encodeMatch(offset, length int) {
+	c.flushLiterals(c.__inputCursor)
+
+	switch {
+	case length == 2:
+		offset--
+		c.putByte(M1_MARKER | (offset&3)<<2)
+		c.putByte(offset >> 2)
+	case length == 3 && c.state == 4 && offset > M2_MAX_OFFSET && offset <= M2_MAX_OFFSET+M1_MAX_OFFSET:
+		offset -= 1 + M2_MAX_OFFSET
+		c.putByte(M1_MARKER | (offset&3)<<2)
+		c.putByte(offset >> 2)
+	case length <= M2_MAX_LEN && offset <= M2_MAX_OFFSET:
+		offset--
+		c.putByte((length-1)<<5 | (offset&7)<<2)
+		c.putByte(offset >> 3)
+	case offset <= M3_MAX_OFFSET:
+		offset--
+		if length <= M3_MAX_LEN {
+			c.putByte(M3_MARKER | (length - 2))
+		} else {
+			c.putByte(M3_MARKER)
+			c.putLength(length - M3_MAX_LEN)
+		}
+		c.putByte(offset << 2)
+		c.putByte(offset >> 6)
+	default:
+		offset -= 0x4000
+		if length <= M4_MAX_LEN {
+			c.putByte(M4_MARKER | (offset>>11)&8 | (length - 2))
+		} else {
+			c.putByte(M4_MARKER | (offset>>11)&8)
+			c.putLength(length - M4_MAX_LEN)
+		}
+		c.putByte(offset << 2)
+		c.putByte(offset >> 6)
+	}
+
+	c.__inputCursor += length
+	c.literalStart = c.__inputCursor
+	c.state = 0
+}
+
+func (c *Compressor) matchLength(position, offset, limit int) int {
+	length := 0
+	for position+length < limit && c.__input[position+length] == c.__input[position+length-offset] {
+		length++
+	}
+	return length
+}
+
+func read32(buf []byte, position int) uint32 {
+	return uint32(buf[position]) | uint32(buf[position+1])<<8 | uint32(buf[position+2])<<16 | uint32(buf[position+3])<<24
+}
+
+// compress1 与LZO1X-1相同的策略: 4字节哈希, 单次探测, 未命中时逐渐加大步长
+func (c *Compressor) compress1() {
+	input := c.__input
+	end := len(input) - 20
+	c.dict = make([]int, 1<<D_BITS)
+	for i := range c.dict {
+		c.dict[i] = -1
+	}
+
+	c.__inputCursor = 4
+	for c.__inputCursor < end {
+		dv := read32(input, c.__inputCursor)
+		h := (dv * 0x1824429D) >> (32 - D_BITS)
+		candidate := c.dict[h]
+		c.dict[h] = c.__inputCursor
+
+		if candidate < 0 || c.__inputCursor-candidate > M4_MAX_OFFSET || read32(input, candidate) != dv {
+			c.__inputCursor += 1 + (c.__inputCursor-c.literalStart)>>5
+			continue
+		}
+
+		offset := c.__inputCursor - candidate
+		length := 4 + c.matchLength(c.__inputCursor+4, offset, end)
+		c.encodeMatch(offset, length)
+	}
+}
+
+func (c *Compressor) hash3(position int) int {
+	v := uint32(c.__input[position]) | uint32(c.__input[position+1])<<8 | uint32(c.__input[position+2])<<16
+	return int((v * 0x9E3779B1) >> (32 - HASH_BITS))
+}
+
+func (c *Compressor) insert(position int) {
+	if position+2 < len(c.__input) {
+		h := c.hash3(position)
+		c.prev[position] = c.head[h]
+		c.head[h] = position
+	}
+}
+
+// find 在哈希链中查找position处收益最高的匹配
+func (c *Compressor) find(position, state int) (offset, length int) {
+	limit := len(c.__input)
+	bestGain := 0
+
+	check := func(o, l int) {
+		if cost := c.matchCost(state, o, l); cost > 0 {
+			// 匹配之后的字面量需要额外的长度字节, 这里按匹配节省的字节数粗略比较
+			if gain := l - cost; gain > bestGain || (gain == bestGain && l > length) {
+				bestGain = gain
+				offset, length = o, l
+			}
+		}
+	}
+
+	// 短字面量之后可以使用2字节的M1匹配
+	if state >= 1 && state <= 3 {
+		for o := 1; o <= M1_MAX_OFFSET && o <= position; o++ {
+			if position+1 < limit && c.__input[position] == c.__input[position-o] && c.__input[position+1] == c.__input[position+1-o] {
+				check(o, 2)
+				break
+			}
+		}
+	}
+
+	if position+2 >= limit {
+		return
+	}
+
+	chain := c.level.MaxChain
+	for candidate := c.head[c.hash3(position)]; candidate >= 0 && chain > 0; candidate = c.prev[candidate] {
+		o := position - candidate
+		if o > M4_MAX_OFFSET {
+			break
+		}
+		if l := c.matchLength(position, o, limit); l >= 3 {
+			check(o, l)
+			if length >= c.level.NiceLength {
+				break
+			}
+		}
+		chain--
+	}
+
+	return
+}
+
+func (c *Compressor) compress999() {
+	c.head = make([]int, 1<<HASH_BITS)
+	for i := range c.head {
+		c.head[i] = -1
+	}
+	c.prev = make([]int, len(c.__input))
+
+	inserted := 0
+	insertUpto := func(end int) {
+		for ; inserted < end; inserted++ {
+			c.insert(inserted)
+		}
+	}
+
+	// 第一个字节必须是字面量
+	c.__inputCursor = 1
+	for c.__inputCursor < len(c.__input) {
+		insertUpto(c.__inputCursor)
+		state := c.state
+		if c.__inputCursor > c.literalStart {
+			state = c.__inputCursor - c.literalStart
+			if state > 4 {
+				state = 4
+			}
+		}
+
+		offset, length := c.find(c.__inputCursor, state)
+		if length == 0 {
+			c.__inputCursor++
+			continue
+		}
+
+		if c.level.Lazy && length < c.level.NiceLength && c.__inputCursor+1 < len(c.__input) {
+			insertUpto(c.__inputCursor + 1)
+			nextState := state + 1
+			if nextState > 4 {
+				nextState = 4
+			}
+			if _, nextLength := c.find(c.__inputCursor+1, nextState); nextLength > length+1 {
+				c.__inputCursor++
+				continue
+			}
+		}
+
+		c.encodeMatch(offset, length)
+	}
+}
+
+func (c *Compressor) Compress() ([]byte, error) {
+	c.__output = make([]byte, 0, len(c.__input)+len(c.__input)/16+64+3)
+
+	if c.level.MaxChain == 0 {
+		if len(c.__input) > 20 {
+			c.compress1()
+		}
+	} else if len(c.__input) > 0 {
+		c.compress999()
+	}
+
+	c.flushLiterals(len(c.__input))
+
+	// end of stream: M4 with offset 0
+	c.putByte(M4_MARKER | 1)
+	c.putByte(0)
+	c.putByte(0)
+
+	return c.__output, nil
+}
+
+func NewCompressor(input []byte, level Level) *Compressor {
+	return &Compressor{
+		level:   level,
+		__input: input,
+	}
+}
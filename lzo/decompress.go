@@ -0,0 +1,197 @@
+package lzo
+
+type Decompressor struct {
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+}
+
+func (d *Decompressor) needInput(n int) error {
+	if d.__inputCursor+n > len(d.__input) {
+		return ErrInputOverrun
+	}
+	return nil
+}
+
+func (d *Decompressor) readByte() (int, error) {
+	if err := d.needInput(1); err != nil {
+		return 0, err
+	}
+	d.__inputCursor++
+	return int(d.__input[d.__inputCursor-1]), nil
+}
+
+func (d *Decompressor) readUint16() (int, error) {
+	if err := d.needInput(2); err != nil {
+		return 0, err
+	}
+	d.__inputCursor += 2
+	return int(d.__input[d.__inputCursor-2]) | int(d.__input[d.__inputCursor-1])<<8, nil
+}
+
+// readLength 读取以0x00扩展的长度, base为该指令的基础长度
+func (d *Decompressor) readLength(base int) (int, error) {
+	t := 0
+	for {
+		b, err := d.readByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != 0 {
+			return t + base + b, nil
+		}
+		t += 255
+		if t > len(d.__input)*255 {
+			return 0, ErrInputOverrun
+		}
+	}
+}
+
+func (d *Decompressor) copyLiterals(n int) error {
+	if err := d.needInput(n); err != nil {
+		return err
+	}
+	d.__output = append(d.__output, d.__input[d.__inputCursor:d.__inputCursor+n]...)
+	d.__inputCursor += n
+	return nil
+}
+
+func (d *Decompressor) copyMatch(offset, length int) error {
+	if offset <= 0 || offset > len(d.__output) {
+		return ErrLookbehindOverrun
+	}
+	position := len(d.__output) - offset
+	for i := 0; i < length; i++ {
+		d.__output = append(d.__output, d.__output[position+i])
+	}
+	return nil
+}
+
+func (d *Decompressor) Decompress() ([]byte, error) {
+	d.__output = make([]byte, 0, len(d.__input)*3)
+
+	// state: 0 上一条指令是匹配且没有附带字面量, 1~3 附带的字面量个数, 4 上一条指令是长字面量
+	state := 0
+
+	if err := d.needInput(1); err != nil {
+		return nil, err
+	}
+	if d.__input[0] > 17 {
+		d.__inputCursor++
+		t := int(d.__input[0]) - 17
+		if err := d.copyLiterals(t); err != nil {
+			return nil, err
+		}
+		if t < 4 {
+			state = t
+		} else {
+			state = 4
+		}
+	}
+
+	for {
+		t, err := d.readByte()
+		if err != nil {
+			return nil, err
+		}
+
+		var offset, length, next int
+
+		switch {
+		case t < 16 && state == 0:
+			// literal run
+			if t == 0 {
+				if t, err = d.readLength(15); err != nil {
+					return nil, err
+				}
+			}
+			if err = d.copyLiterals(t + 3); err != nil {
+				return nil, err
+			}
+			state = 4
+			continue
+		case t < 16 && state != 4:
+			// M1: 2字节匹配, 前面有1~3个字面量
+			b, err := d.readByte()
+			if err != nil {
+				return nil, err
+			}
+			next = t & 3
+			offset = 1 + (t >> 2) + (b << 2)
+			length = 2
+		case t < 16:
+			// M1: 3字节匹配, 前面是长字面量
+			b, err := d.readByte()
+			if err != nil {
+				return nil, err
+			}
+			next = t & 3
+			offset = 1 + M2_MAX_OFFSET + (t >> 2) + (b << 2)
+			length = 3
+		case t >= 64:
+			// M2
+			b, err := d.readByte()
+			if err != nil {
+				return nil, err
+			}
+			next = t & 3
+			offset = 1 + ((t >> 2) & 7) + (b << 3)
+			length = (t >> 5) + 1
+		case t >= 32:
+			// M3
+			length = t&31 + 2
+			if length == 2 {
+				if length, err = d.readLength(31 + 2); err != nil {
+					return nil, err
+				}
+			}
+			v, err := d.readUint16()
+			if err != nil {
+				return nil, err
+			}
+			next = v & 3
+			offset = 1 + (v >> 2)
+		default:
+			// M4
+			length = t&7 + 2
+			if length == 2 {
+				if length, err = d.readLength(7 + 2); err != nil {
+					return nil, err
+				}
+			}
+			v, err := d.readUint16()
+			if err != nil {
+				return nil, err
+			}
+			next = v & 3
+			offset = (t&8)<<11 + (v >> 2)
+			if offset == 0 {
+				// end of stream
+				if length != 3 {
+					return nil, ErrEOFNotFound
+				}
+				if d.__inputCursor < len(d.__input) {
+					return d.__output, ErrInputNotConsumed
+				}
+				return d.__output, nil
+			}
+			offset += 0x4000
+		}
+
+		if err = d.copyMatch(offset, length); err != nil {
+			return nil, err
+		}
+
+		// 匹配之后紧跟的0~3个字面量
+		if err = d.copyLiterals(next); err != nil {
+			return nil, err
+		}
+		state = next
+	}
+}
+
+func NewDecompressor(input []byte) *Decompressor {
+	return &Decompressor{
+		__input: input,
+	}
+}
@@ -0,0 +1,41 @@
+package lzo
+
+import "fmt"
+
+const (
+	M1_MAX_OFFSET = 0x0400
+	M2_MAX_OFFSET = 0x0800
+	M3_MAX_OFFSET = 0x4000
+	M4_MAX_OFFSET = 0xBFFF
+
+	M2_MIN_LEN = 3
+	M2_MAX_LEN = 8
+	M3_MAX_LEN = 33
+	M4_MAX_LEN = 9
+
+	M1_MARKER = 0
+	M2_MARKER = 64
+	M3_MARKER = 32
+	M4_MARKER = 16
+)
+
+var (
+	ErrInputOverrun      = fmt.Errorf("LZO1X: input overrun")
+	ErrLookbehindOverrun = fmt.Errorf("LZO1X: lookbehind overrun")
+	ErrInputNotConsumed  = fmt.Errorf("LZO1X: input not consumed")
+	ErrEOFNotFound       = fmt.Errorf("LZO1X: end of stream not found")
+)
+
+// Compress LZO1X-1
+func Compress(input []byte) ([]byte, error) {
+	return NewCompressor(input, Level1).Compress()
+}
+
+// Compress999 LZO1X-999 (更慢, 压缩率更高)
+func Compress999(input []byte) ([]byte, error) {
+	return NewCompressor(input, Level999).Compress()
+}
+
+func Decompress(input []byte) ([]byte, error) {
+	return NewDecompressor(input).Decompress()
+}
@@ -0,0 +1,179 @@
+package lzss
+
+const (
+	HASH_BITS = 15
+	MAX_CHAIN = 64
+)
+
+type Compressor struct {
+	params Params
+
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+
+	// data 为填充字节前缀(仅OffsetAbsolute)加上输入, base 为输入在data中的起始位置
+	data []byte
+	base int
+
+	flagPosition int
+	flagCount    int
+
+	head []int
+	prev []int
+}
+
+func (c *Compressor) putFlag(bit int) {
+	if c.flagCount == 0 {
+		c.flagPosition = len(c.__output)
+		c.__output = append(c.__output, 0)
+	}
+	if bit != 0 {
+		if c.params.FlagMSBFirst {
+			c.__output[c.flagPosition] |= 0x80 >> c.flagCount
+		} else {
+			c.__output[c.flagPosition] |= 1 << c.flagCount
+		}
+	}
+	c.flagCount = (c.flagCount + 1) & 7
+}
+
+func (c *Compressor) hash(position int) int {
+	v := uint32(c.data[position]) | uint32(c.data[position+1])<<8 | uint32(c.data[position+2])<<16
+	return int((v * 0x9E3779B1) >> (32 - HASH_BITS))
+}
+
+func (c *Compressor) insert(position int) {
+	if position+2 < len(c.data) {
+		h := c.hash(position)
+		c.prev[position] = c.head[h]
+		c.head[h] = position
+	}
+}
+
+func (c *Compressor) maxDistance() int {
+	size := c.params.WindowSize()
+	if c.params.OffsetMode == OffsetRelative && size-1+c.params.OffsetBias < size {
+		return size - 1 + c.params.OffsetBias
+	}
+	return size
+}
+
+func (c *Compressor) find(position int) (distance, length int) {
+	if position+2 >= len(c.data) {
+		return
+	}
+
+	maxDistance := c.maxDistance()
+	minDistance := 1
+	if c.params.OffsetMode == OffsetRelative && c.params.OffsetBias > 1 {
+		minDistance = c.params.OffsetBias
+	}
+	maxMatch := c.params.MaxMatch()
+	if remain := len(c.data) - position; maxMatch > remain {
+		maxMatch = remain
+	}
+
+	chain := MAX_CHAIN
+	for candidate := c.head[c.hash(position)]; candidate >= 0 && chain > 0; candidate = c.prev[candidate] {
+		d := position - candidate
+		if d > maxDistance {
+			break
+		}
+		chain--
+		if d < minDistance {
+			continue
+		}
+		l := 0
+		for l < maxMatch && c.data[position+l] == c.data[candidate+l] {
+			l++
+		}
+		if l > length {
+			distance, length = d, l
+			if l == maxMatch {
+				break
+			}
+		}
+	}
+
+	if length < c.params.MinMatch {
+		return 0, 0
+	}
+	return
+}
+
+func (c *Compressor) Compress() ([]byte, error) {
+	if err := c.params.Validate(); err != nil {
+		return nil, err
+	}
+
+	if c.params.OffsetMode == OffsetAbsolute {
+		// 环形缓冲区中尚未写入的位置为填充字节, 编码器同样可以引用
+		c.base = c.params.WindowSize()
+	}
+	c.data = make([]byte, c.base+len(c.__input))
+	for i := 0; i < c.base; i++ {
+		c.data[i] = c.params.FillByte
+	}
+	copy(c.data[c.base:], c.__input)
+
+	c.__output = make([]byte, 0, len(c.__input)+len(c.__input)/8+1)
+	c.head = make([]int, 1<<HASH_BITS)
+	for i := range c.head {
+		c.head[i] = -1
+	}
+	c.prev = make([]int, len(c.data))
+
+	// 只需要插入窗口范围内的填充字节;
+	// LZSS.C 只填充了 InitialPosition 之前的位置, 因此不引用之后的位置
+	start := c.base - c.maxDistance()
+	if p := c.params.InitialPosition & (c.params.WindowSize() - 1); p > 0 && start < c.base-p {
+		start = c.base - p
+	}
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < c.base; i++ {
+		c.insert(i)
+	}
+
+	mask := c.params.WindowSize() - 1
+	literalFlag := c.params.LiteralFlag
+
+	for position := c.base; position < len(c.data); {
+		distance, length := c.find(position)
+		if length == 0 {
+			c.putFlag(literalFlag)
+			c.__output = append(c.__output, c.data[position])
+			c.insert(position)
+			position++
+			continue
+		}
+
+		var offset int
+		if c.params.OffsetMode == OffsetAbsolute {
+			offset = (c.params.InitialPosition + position - distance - c.base) & mask
+		} else {
+			offset = distance - c.params.OffsetBias
+		}
+		b0, b1 := c.params.packToken(offset, length-c.params.MinMatch)
+		c.putFlag(literalFlag ^ 1)
+		c.__output = append(c.__output, b0, b1)
+
+		for i := 0; i < length; i++ {
+			c.insert(position + i)
+		}
+		position += length
+	}
+
+	c.__inputCursor = len(c.__input)
+
+	return c.__output, nil
+}
+
+func NewCompressor(input []byte, params Params) *Compressor {
+	return &Compressor{
+		params:  params,
+		__input: input,
+	}
+}
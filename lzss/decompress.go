@@ -0,0 +1,81 @@
+package lzss
+
+type Decompressor struct {
+	params Params
+
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+}
+
+func (d *Decompressor) Decompress() ([]byte, error) {
+	if err := d.params.Validate(); err != nil {
+		return nil, err
+	}
+
+	size := d.params.WindowSize()
+	mask := size - 1
+	ring := make([]byte, size)
+	for i := range ring {
+		ring[i] = d.params.FillByte
+	}
+	r := d.params.InitialPosition & mask
+
+	d.__output = make([]byte, 0, len(d.__input)*2)
+
+	// 与LZSS.C一致, 输入耗尽(包括token只剩一个字节)时结束
+	for d.__inputCursor < len(d.__input) {
+		flags := d.__input[d.__inputCursor]
+		d.__inputCursor++
+
+		for i := 0; i < 8 && d.__inputCursor < len(d.__input); i++ {
+			var bit int
+			if d.params.FlagMSBFirst {
+				bit = int(flags>>(7-i)) & 1
+			} else {
+				bit = int(flags>>i) & 1
+			}
+
+			if bit == d.params.LiteralFlag {
+				c := d.__input[d.__inputCursor]
+				d.__inputCursor++
+				d.__output = append(d.__output, c)
+				ring[r] = c
+				r = (r + 1) & mask
+				continue
+			}
+
+			if d.__inputCursor+2 > len(d.__input) {
+				d.__inputCursor = len(d.__input)
+				break
+			}
+			position, length := d.params.unpackToken(d.__input[d.__inputCursor], d.__input[d.__inputCursor+1])
+			d.__inputCursor += 2
+			length += d.params.MinMatch
+
+			if d.params.OffsetMode == OffsetRelative {
+				distance := position + d.params.OffsetBias
+				if distance == 0 || distance > size {
+					return nil, ErrInvalidData
+				}
+				position = (r - distance) & mask
+			}
+
+			for k := 0; k < length; k++ {
+				c := ring[(position+k)&mask]
+				d.__output = append(d.__output, c)
+				ring[r] = c
+				r = (r + 1) & mask
+			}
+		}
+	}
+
+	return d.__output, nil
+}
+
+func NewDecompressor(input []byte, params Params) *Decompressor {
+	return &Decompressor{
+		params:  params,
+		__input: input,
+	}
+}
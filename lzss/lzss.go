@@ -0,0 +1,130 @@
+package lzss
+
+import "fmt"
+
+type OffsetMode int
+
+const (
+	// OffsetAbsolute 匹配位置为环形缓冲区中的绝对位置(Okumura LZSS.C)
+	OffsetAbsolute OffsetMode = iota
+	// OffsetRelative 匹配位置为距当前位置的距离, 存储值为 distance - OffsetBias
+	OffsetRelative
+)
+
+type TokenFormat int
+
+const (
+	// TokenOkumura 第1字节为位置的低8位, 第2字节高位为位置的剩余部分, 低LengthBits位为长度
+	TokenOkumura TokenFormat = iota
+	// TokenBigEndian 16位大端序, 长度在高LengthBits位, 位置在低WindowBits位 (GBA/NDS LZ10)
+	TokenBigEndian
+	// TokenLittleEndian 16位小端序, 位置在高WindowBits位, 长度在低LengthBits位
+	TokenLittleEndian
+)
+
+type Params struct {
+	WindowBits      int
+	LengthBits      int
+	MinMatch        int
+	FillByte        uint8
+	InitialPosition int
+	// LiteralFlag 表示字面量的flag位的值(0或1)
+	LiteralFlag  int
+	FlagMSBFirst bool
+	OffsetMode   OffsetMode
+	OffsetBias   int
+	TokenFormat  TokenFormat
+}
+
+var (
+	ErrInvalidParams = fmt.Errorf("the LZSS parameters are invalid")
+	ErrInvalidData   = fmt.Errorf("the input data is invalid")
+)
+
+var (
+	// Okumura Haruhiko Okumura的LZSS.C (N=4096, F=18, THRESHOLD=2, 以空格填充), 同时也是Apple complzss使用的格式
+	Okumura = Params{
+		WindowBits:      12,
+		LengthBits:      4,
+		MinMatch:        3,
+		FillByte:        ' ',
+		InitialPosition: 4096 - 18,
+		LiteralFlag:     1,
+		FlagMSBFirst:    false,
+		OffsetMode:      OffsetAbsolute,
+		TokenFormat:     TokenOkumura,
+	}
+
+	// OkumuraZero 与Okumura相同, 但环形缓冲区以0填充, 常见于固件和游戏资源
+	OkumuraZero = Params{
+		WindowBits:      12,
+		LengthBits:      4,
+		MinMatch:        3,
+		FillByte:        0,
+		InitialPosition: 4096 - 18,
+		LiteralFlag:     1,
+		FlagMSBFirst:    false,
+		OffsetMode:      OffsetAbsolute,
+		TokenFormat:     TokenOkumura,
+	}
+
+	// LZ10 GBA/NDS BIOS LZ77(类型0x10)的数据部分, 不包含4字节的头部
+	LZ10 = Params{
+		WindowBits:   12,
+		LengthBits:   4,
+		MinMatch:     3,
+		LiteralFlag:  0,
+		FlagMSBFirst: true,
+		OffsetMode:   OffsetRelative,
+		OffsetBias:   1,
+		TokenFormat:  TokenBigEndian,
+	}
+)
+
+func (p *Params) Validate() error {
+	if p.WindowBits < 8 || p.LengthBits < 1 || p.WindowBits+p.LengthBits != 16 ||
+		p.MinMatch < 1 || (p.LiteralFlag != 0 && p.LiteralFlag != 1) || p.OffsetBias < 0 {
+		return ErrInvalidParams
+	}
+	return nil
+}
+
+func (p *Params) WindowSize() int {
+	return 1 << p.WindowBits
+}
+
+func (p *Params) MaxMatch() int {
+	return p.MinMatch + 1<<p.LengthBits - 1
+}
+
+func (p *Params) packToken(position, length int) (byte, byte) {
+	switch p.TokenFormat {
+	case TokenBigEndian:
+		v := length<<p.WindowBits | position
+		return byte(v >> 8), byte(v)
+	case TokenLittleEndian:
+		v := position<<p.LengthBits | length
+		return byte(v), byte(v >> 8)
+	}
+	return byte(position), byte((position>>8)<<p.LengthBits | length)
+}
+
+func (p *Params) unpackToken(b0, b1 byte) (position, length int) {
+	switch p.TokenFormat {
+	case TokenBigEndian:
+		v := int(b0)<<8 | int(b1)
+		return v & (1<<p.WindowBits - 1), v >> p.WindowBits
+	case TokenLittleEndian:
+		v := int(b1)<<8 | int(b0)
+		return v >> p.LengthBits, v & (1<<p.LengthBits - 1)
+	}
+	return int(b0) | int(b1>>p.LengthBits)<<8, int(b1) & (1<<p.LengthBits - 1)
+}
+
+func Compress(input []byte, params Params) ([]byte, error) {
+	return NewCompressor(input, params).Compress()
+}
+
+func Decompress(input []byte, params Params) ([]byte, error) {
+	return NewDecompressor(input, params).Decompress()
+}
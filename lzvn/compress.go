@@ -0,0 +1,202 @@
+package lzvn
+
+const (
+	HASH_BITS = 14
+	MAX_CHAIN = 32
+)
+
+type Compressor struct {
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+
+	literalStart int
+	distance     int
+
+	head []int
+	prev []int
+}
+
+func (c *Compressor) putByte(b int) {
+	c.__output = append(c.__output, byte(b))
+}
+
+// emitLiterals 使用sml_l/lrg_l输出字面量
+func (c *Compressor) emitLiterals(end int) {
+	for c.literalStart < end {
+		n := end - c.literalStart
+		if n > 271 {
+			n = 271
+		}
+		if n < 16 {
+			c.putByte(0xE0 | n)
+		} else {
+			c.putByte(0xE0)
+			c.putByte(n - 16)
+		}
+		c.__output = append(c.__output, c.__input[c.literalStart:c.literalStart+n]...)
+		c.literalStart += n
+	}
+}
+
+// emitMatchOnly 使用sml_m/lrg_m输出与上一个distance相同的匹配
+func (c *Compressor) emitMatchOnly(match int) {
+	for match > 0 {
+		n := match
+		if n > 271 {
+			n = 271
+		}
+		if n < 16 {
+			c.putByte(0xF0 | n)
+		} else {
+			c.putByte(0xF0)
+			c.putByte(n - 16)
+		}
+		match -= n
+	}
+}
+
+func (c *Compressor) emitMatch(distance, match int) {
+	// 最多3个字面量可以和匹配合并到一个指令中
+	if c.__inputCursor-c.literalStart > 3 {
+		c.emitLiterals(c.__inputCursor - 3)
+	}
+	literal := c.__inputCursor - c.literalStart
+	literals := c.__input[c.literalStart:c.__inputCursor]
+	c.literalStart = c.__inputCursor
+
+	// L为2或3时, MMM不能超过3, 否则会与med_d/sml_l/lrg_l等指令冲突
+	maxMatch := 10
+	if literal >= 2 {
+		maxMatch = 6
+	}
+
+	if literal == 0 && distance == c.distance {
+		c.emitMatchOnly(match)
+	} else {
+		first := match
+		if distance == c.distance {
+			// pre_d: LLMMM110
+			first = min(first, maxMatch)
+			c.putByte(literal<<6 | (first-3)<<3 | 6)
+		} else if distance < 0x600 && match <= maxMatch {
+			// sml_d: LLMMMDDD DDDDDDDD
+			c.putByte(literal<<6 | (first-3)<<3 | distance>>8)
+			c.putByte(distance & 0xFF)
+		} else if distance < 0x4000 {
+			// med_d: 101LLMMM DDDDDDMM DDDDDDDD
+			first = min(first, 34)
+			m := first - 3
+			c.putByte(0xA0 | literal<<3 | m>>2)
+			v := distance<<2 | m&3
+			c.putByte(v & 0xFF)
+			c.putByte(v >> 8)
+		} else {
+			// lrg_d: LLMMM111 DDDDDDDD DDDDDDDD
+			first = min(first, maxMatch)
+			c.putByte(literal<<6 | (first-3)<<3 | 7)
+			c.putByte(distance & 0xFF)
+			c.putByte(distance >> 8)
+		}
+		c.__output = append(c.__output, literals...)
+		c.emitMatchOnly(match - first)
+	}
+
+	c.distance = distance
+	c.__inputCursor += match
+	c.literalStart = c.__inputCursor
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (c *Compressor) hash(position int) int {
+	v := uint32(c.__input[position]) | uint32(c.__input[position+1])<<8 | uint32(c.__input[position+2])<<16
+	return int((v * 0x9E3779B1) >> (32 - HASH_BITS))
+}
+
+func (c *Compressor) insert(position int) {
+	if position+2 < len(c.__input) {
+		h := c.hash(position)
+		c.prev[position] = c.head[h]
+		c.head[h] = position
+	}
+}
+
+func (c *Compressor) matchLength(position, distance int) int {
+	length := 0
+	for position+length < len(c.__input) && c.__input[position+length] == c.__input[position+length-distance] {
+		length++
+	}
+	return length
+}
+
+func (c *Compressor) find(position int) (distance, length int) {
+	// 上一个distance可以用更短的指令编码
+	if c.distance > 0 && c.distance <= position {
+		if l := c.matchLength(position, c.distance); l >= MIN_MATCH {
+			distance, length = c.distance, l
+		}
+	}
+
+	if position+2 >= len(c.__input) {
+		return
+	}
+
+	chain := MAX_CHAIN
+	for candidate := c.head[c.hash(position)]; candidate >= 0 && chain > 0; candidate = c.prev[candidate] {
+		d := position - candidate
+		if d > MAX_DISTANCE {
+			break
+		}
+		// 比上一个distance的匹配至少长2个字节才值得使用新的distance
+		need := length
+		if length > 0 && distance == c.distance {
+			need++
+		}
+		if l := c.matchLength(position, d); l >= MIN_MATCH && l > need {
+			distance, length = d, l
+		}
+		chain--
+	}
+
+	return
+}
+
+func (c *Compressor) Compress() ([]byte, error) {
+	c.__output = make([]byte, 0, len(c.__input)+len(c.__input)/8+16)
+	c.head = make([]int, 1<<HASH_BITS)
+	for i := range c.head {
+		c.head[i] = -1
+	}
+	c.prev = make([]int, len(c.__input))
+
+	for c.__inputCursor < len(c.__input) {
+		start := c.__inputCursor
+		if distance, length := c.find(c.__inputCursor); length > 0 {
+			c.emitMatch(distance, length)
+		} else {
+			c.__inputCursor++
+		}
+		for i := start; i < c.__inputCursor; i++ {
+			c.insert(i)
+		}
+	}
+
+	c.emitLiterals(len(c.__input))
+
+	// eos: 0x06 + 7字节填充
+	c.__output = append(c.__output, 0x06, 0, 0, 0, 0, 0, 0, 0)
+
+	return c.__output, nil
+}
+
+func NewCompressor(input []byte) *Compressor {
+	return &Compressor{
+		__input: input,
+	}
+}
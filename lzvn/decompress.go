@@ -0,0 +1,126 @@
+package lzvn
+
+import (
+	"encoding/binary"
+)
+
+type Decompressor struct {
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+}
+
+func (d *Decompressor) need(n int) error {
+	if d.__inputCursor+n > len(d.__input) {
+		return ErrInvalidData
+	}
+	return nil
+}
+
+func (d *Decompressor) Decompress() ([]byte, error) {
+	d.__output = make([]byte, 0, len(d.__input)*3)
+	distance := 0
+
+	for {
+		if err := d.need(1); err != nil {
+			return nil, err
+		}
+		opcode := int(d.__input[d.__inputCursor])
+
+		var literal, match, length int
+
+		switch {
+		case opcode == 0x06:
+			// eos, 后面跟7个字节的填充
+			return d.__output, nil
+		case opcode == 0x0E || opcode == 0x16:
+			// nop
+			d.__inputCursor++
+			continue
+		case opcode < 0x40 && opcode&7 == 6:
+			// udef
+			return nil, ErrInvalidData
+		case opcode >= 0xA0 && opcode < 0xC0:
+			// med_d: 101LLMMM DDDDDDMM DDDDDDDD
+			if err := d.need(3); err != nil {
+				return nil, err
+			}
+			v := int(binary.LittleEndian.Uint16(d.__input[d.__inputCursor+1:]))
+			length = 3
+			literal = (opcode >> 3) & 3
+			match = ((opcode&7)<<2 | v&3) + 3
+			distance = v >> 2
+		case opcode == 0xE0:
+			// lrg_l: 11100000 LLLLLLLL
+			if err := d.need(2); err != nil {
+				return nil, err
+			}
+			length = 2
+			literal = int(d.__input[d.__inputCursor+1]) + 16
+		case opcode > 0xE0 && opcode < 0xF0:
+			// sml_l: 1110LLLL
+			length = 1
+			literal = opcode & 0xF
+		case opcode == 0xF0:
+			// lrg_m: 11110000 MMMMMMMM
+			if err := d.need(2); err != nil {
+				return nil, err
+			}
+			length = 2
+			match = int(d.__input[d.__inputCursor+1]) + 16
+		case opcode > 0xF0:
+			// sml_m: 1111MMMM
+			length = 1
+			match = opcode & 0xF
+		case opcode&7 == 7:
+			// lrg_d: LLMMM111 DDDDDDDD DDDDDDDD
+			if err := d.need(3); err != nil {
+				return nil, err
+			}
+			length = 3
+			literal = opcode >> 6
+			match = (opcode>>3)&7 + 3
+			distance = int(binary.LittleEndian.Uint16(d.__input[d.__inputCursor+1:]))
+		case opcode&7 == 6:
+			// pre_d: LLMMM110
+			length = 1
+			literal = opcode >> 6
+			match = (opcode>>3)&7 + 3
+		default:
+			// sml_d: LLMMMDDD DDDDDDDD
+			if err := d.need(2); err != nil {
+				return nil, err
+			}
+			length = 2
+			literal = opcode >> 6
+			match = (opcode>>3)&7 + 3
+			distance = (opcode&7)<<8 | int(d.__input[d.__inputCursor+1])
+		}
+
+		d.__inputCursor += length
+
+		if literal > 0 {
+			if err := d.need(literal); err != nil {
+				return nil, err
+			}
+			d.__output = append(d.__output, d.__input[d.__inputCursor:d.__inputCursor+literal]...)
+			d.__inputCursor += literal
+		}
+
+		if match > 0 {
+			if distance == 0 || distance > len(d.__output) {
+				return nil, ErrInvalidData
+			}
+			position := len(d.__output) - distance
+			for i := 0; i < match; i++ {
+				d.__output = append(d.__output, d.__output[position+i])
+			}
+		}
+	}
+}
+
+func NewDecompressor(input []byte) *Decompressor {
+	return &Decompressor{
+		__input: input,
+	}
+}
@@ -0,0 +1,164 @@
+package lzvn
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	MAX_DISTANCE = 0xFFFF
+	MIN_MATCH    = 3
+)
+
+var (
+	ErrInvalidData   = fmt.Errorf("the input data is invalid")
+	ErrInvalidBlock  = fmt.Errorf("the block header is invalid")
+	ErrLZFSEBlock    = fmt.Errorf("LZFSE (bvx1/bvx2) blocks are not supported")
+	ErrMissingEndTag = fmt.Errorf("end of stream block (bvx$) not found")
+)
+
+var (
+	MagicLZVN         = [4]byte{'b', 'v', 'x', 'n'}
+	MagicUncompressed = [4]byte{'b', 'v', 'x', '-'}
+	MagicEndOfStream  = [4]byte{'b', 'v', 'x', '$'}
+	MagicLZFSEv1      = [4]byte{'b', 'v', 'x', '1'}
+	MagicLZFSEv2      = [4]byte{'b', 'v', 'x', '2'}
+)
+
+// BlockHeader LZFSE容器中的块头, bvxn为12字节, bvx-为8字节, bvx$为4字节
+type BlockHeader struct {
+	Magic        [4]byte
+	RawBytes     uint32
+	PayloadBytes uint32
+}
+
+// Size 返回块头本身的长度
+func (h *BlockHeader) Size() int {
+	switch h.Magic {
+	case MagicLZVN:
+		return 12
+	case MagicUncompressed:
+		return 8
+	}
+	return 4
+}
+
+func ParseBlockHeader(input []byte) (*BlockHeader, error) {
+	if len(input) < 4 {
+		return nil, ErrInvalidBlock
+	}
+
+	header := &BlockHeader{}
+	copy(header.Magic[:], input)
+
+	switch header.Magic {
+	case MagicEndOfStream:
+	case MagicUncompressed:
+		if len(input) < 8 {
+			return nil, ErrInvalidBlock
+		}
+		header.RawBytes = binary.LittleEndian.Uint32(input[4:])
+		header.PayloadBytes = header.RawBytes
+	case MagicLZVN:
+		if len(input) < 12 {
+			return nil, ErrInvalidBlock
+		}
+		header.RawBytes = binary.LittleEndian.Uint32(input[4:])
+		header.PayloadBytes = binary.LittleEndian.Uint32(input[8:])
+	case MagicLZFSEv1, MagicLZFSEv2:
+		return header, ErrLZFSEBlock
+	default:
+		return nil, ErrInvalidBlock
+	}
+
+	return header, nil
+}
+
+func (h *BlockHeader) Bytes() []byte {
+	buf := make([]byte, h.Size())
+	copy(buf, h.Magic[:])
+	switch h.Magic {
+	case MagicLZVN:
+		binary.LittleEndian.PutUint32(buf[4:], h.RawBytes)
+		binary.LittleEndian.PutUint32(buf[8:], h.PayloadBytes)
+	case MagicUncompressed:
+		binary.LittleEndian.PutUint32(buf[4:], h.RawBytes)
+	}
+	return buf
+}
+
+// DecompressBlocks 解析由bvxn/bvx-块组成、以bvx$结尾的数据流
+func DecompressBlocks(input []byte) ([]byte, error) {
+	result := make([]byte, 0, len(input)*2)
+
+	for cursor := 0; ; {
+		header, err := ParseBlockHeader(input[cursor:])
+		if err != nil {
+			if err == ErrInvalidBlock && cursor == len(input) {
+				return nil, ErrMissingEndTag
+			}
+			return nil, err
+		}
+
+		if header.Magic == MagicEndOfStream {
+			return result, nil
+		}
+
+		cursor += header.Size()
+		if cursor+int(header.PayloadBytes) > len(input) {
+			return nil, ErrInvalidBlock
+		}
+		payload := input[cursor : cursor+int(header.PayloadBytes)]
+		cursor += int(header.PayloadBytes)
+
+		if header.Magic == MagicUncompressed {
+			result = append(result, payload...)
+			continue
+		}
+
+		decompressed, err := NewDecompressor(payload).Decompress()
+		if err != nil {
+			return nil, err
+		}
+		if len(decompressed) != int(header.RawBytes) {
+			return nil, ErrInvalidData
+		}
+		result = append(result, decompressed...)
+	}
+}
+
+// CompressBlocks 生成bvxn(无法压缩时为bvx-)块, 以bvx$结尾
+func CompressBlocks(input []byte) ([]byte, error) {
+	result := make([]byte, 0, len(input)/2+16)
+
+	if len(input) > 0 {
+		payload, err := Compress(input)
+		if err != nil {
+			return nil, err
+		}
+
+		header := &BlockHeader{
+			Magic:        MagicLZVN,
+			RawBytes:     uint32(len(input)),
+			PayloadBytes: uint32(len(payload)),
+		}
+		if len(payload) >= len(input) {
+			header.Magic = MagicUncompressed
+			header.PayloadBytes = header.RawBytes
+			payload = input
+		}
+
+		result = append(result, header.Bytes()...)
+		result = append(result, payload...)
+	}
+
+	return append(result, MagicEndOfStream[:]...), nil
+}
+
+func Compress(input []byte) ([]byte, error) {
+	return NewCompressor(input).Compress()
+}
+
+func Decompress(input []byte) ([]byte, error) {
+	return NewDecompressor(input).Decompress()
+}
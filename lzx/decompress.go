@@ -0,0 +1,441 @@
+package lzx
+
+import "encoding/binary"
+
+// bitReader 按16位小端字读取, 每个字从最高位开始
+type bitReader struct {
+	input  []byte
+	cursor int
+	buffer uint64
+	count  int
+}
+
+func (r *bitReader) ensure(n int) {
+	for r.count < n {
+		word := uint64(0)
+		if r.cursor+2 <= len(r.input) {
+			word = uint64(binary.LittleEndian.Uint16(r.input[r.cursor:]))
+		}
+		r.cursor += 2
+		r.buffer |= word << uint(48-r.count)
+		r.count += 16
+	}
+}
+
+func (r *bitReader) peek(n int) uint32 {
+	r.ensure(n)
+	return uint32(r.buffer >> uint(64-n))
+}
+
+func (r *bitReader) consume(n int) {
+	r.buffer <<= uint(n)
+	r.count -= n
+}
+
+func (r *bitReader) readBits(n int) int {
+	if n == 0 {
+		return 0
+	}
+	v := r.peek(n)
+	r.consume(n)
+	return int(v)
+}
+
+// alignFrame 丢弃当前16位字中剩余的位
+func (r *bitReader) alignFrame() {
+	r.consume(r.count % 16)
+}
+
+// alignUncompressed 未压缩块头前填充1~16位, 之后按字节读取
+func (r *bitReader) alignUncompressed() {
+	if r.count%16 != 0 {
+		r.consume(r.count % 16)
+	} else if r.count == 0 {
+		r.cursor += 2
+	} else {
+		r.consume(16)
+	}
+	r.cursor -= r.count / 8
+	r.buffer = 0
+	r.count = 0
+}
+
+type huffman struct {
+	lengths []uint8
+	table   []uint16
+}
+
+func newHuffman(symbols int) *huffman {
+	return &huffman{
+		lengths: make([]uint8, symbols),
+		table:   make([]uint16, 1<<MAX_CODE_BITS),
+	}
+}
+
+// build 按(码长, 符号)顺序构建canonical编码的查找表, 允许不完整的码表
+func (h *huffman) build() error {
+	for i := range h.table {
+		h.table[i] = 0xFFFF
+	}
+
+	code := 0
+	for length := 1; length <= MAX_CODE_BITS; length++ {
+		for symbol, l := range h.lengths {
+			if int(l) != length {
+				continue
+			}
+			start := code << (MAX_CODE_BITS - length)
+			end := start + 1<<(MAX_CODE_BITS-length)
+			if end > len(h.table) {
+				return ErrInvalidTable
+			}
+			for i := start; i < end; i++ {
+				h.table[i] = uint16(symbol)
+			}
+			code++
+		}
+		code <<= 1
+	}
+	return nil
+}
+
+func (h *huffman) decode(r *bitReader) (int, error) {
+	symbol := h.table[r.peek(MAX_CODE_BITS)]
+	if symbol == 0xFFFF {
+		return 0, ErrInvalidData
+	}
+	r.consume(int(h.lengths[symbol]))
+	return int(symbol), nil
+}
+
+type Decompressor struct {
+	reader bitReader
+	wim    bool
+
+	windowBits int
+	mainSize   int
+
+	__output []byte
+	// Offset 解压数据在整个流中的起始位置, 用于E8转换(CHM从reset点开始解压时需要)
+	Offset int
+
+	e8FileSize  int
+	blockType   int
+	blockRemain int
+	blockSize   int
+	r0, r1, r2  int
+	pretree     *huffman
+	mainTree    *huffman
+	lengthTree  *huffman
+	alignedTree *huffman
+}
+
+func (d *Decompressor) readLengths(lengths []uint8) error {
+	for i := 0; i < PRETREE_SYMBOLS; i++ {
+		d.pretree.lengths[i] = uint8(d.reader.readBits(4))
+	}
+	if err := d.pretree.build(); err != nil {
+		return err
+	}
+
+	for x := 0; x < len(lengths); {
+		z, err := d.pretree.decode(&d.reader)
+		if err != nil {
+			return err
+		}
+
+		switch z {
+		case 17, 18:
+			var run int
+			if z == 17 {
+				run = d.reader.readBits(4) + 4
+			} else {
+				run = d.reader.readBits(5) + 20
+			}
+			if x+run > len(lengths) {
+				return ErrInvalidData
+			}
+			for ; run > 0; run-- {
+				lengths[x] = 0
+				x++
+			}
+		case 19:
+			run := d.reader.readBits(1) + 4
+			if z, err = d.pretree.decode(&d.reader); err != nil {
+				return err
+			}
+			if z > 16 || x+run > len(lengths) {
+				return ErrInvalidData
+			}
+			v := (int(lengths[x]) - z + 17) % 17
+			for ; run > 0; run-- {
+				lengths[x] = uint8(v)
+				x++
+			}
+		default:
+			lengths[x] = uint8((int(lengths[x]) - z + 17) % 17)
+			x++
+		}
+	}
+	return nil
+}
+
+func (d *Decompressor) reset() {
+	d.r0, d.r1, d.r2 = 1, 1, 1
+	for i := range d.mainTree.lengths {
+		d.mainTree.lengths[i] = 0
+	}
+	for i := range d.lengthTree.lengths {
+		d.lengthTree.lengths[i] = 0
+	}
+	d.blockRemain = 0
+	d.blockType = 0
+}
+
+func (d *Decompressor) readBlockHeader() error {
+	// 奇数长度的未压缩块后面有一个填充字节
+	if d.blockType == BLOCKTYPE_UNCOMPRESSED && d.blockSize&1 != 0 {
+		d.reader.cursor++
+	}
+
+	d.blockType = d.reader.readBits(3)
+	if d.wim {
+		if d.reader.readBits(1) == 1 {
+			d.blockSize = WIM_DEFAULT_BLOCK_SIZE
+		} else {
+			d.blockSize = d.reader.readBits(16)
+			if d.windowBits >= 16 {
+				d.blockSize = d.blockSize<<8 | d.reader.readBits(8)
+			}
+		}
+	} else {
+		d.blockSize = d.reader.readBits(16)<<8 | d.reader.readBits(8)
+	}
+	d.blockRemain = d.blockSize
+
+	switch d.blockType {
+	case BLOCKTYPE_ALIGNED, BLOCKTYPE_VERBATIM:
+		if d.blockType == BLOCKTYPE_ALIGNED {
+			for i := 0; i < ALIGNED_SYMBOLS; i++ {
+				d.alignedTree.lengths[i] = uint8(d.reader.readBits(3))
+			}
+			if err := d.alignedTree.build(); err != nil {
+				return err
+			}
+		}
+		if err := d.readLengths(d.mainTree.lengths[:NUM_CHARS]); err != nil {
+			return err
+		}
+		if err := d.readLengths(d.mainTree.lengths[NUM_CHARS:]); err != nil {
+			return err
+		}
+		if err := d.mainTree.build(); err != nil {
+			return err
+		}
+		if err := d.readLengths(d.lengthTree.lengths); err != nil {
+			return err
+		}
+		if err := d.lengthTree.build(); err != nil {
+			return err
+		}
+	case BLOCKTYPE_UNCOMPRESSED:
+		d.reader.alignUncompressed()
+		if d.reader.cursor+12 > len(d.reader.input) {
+			return ErrInvalidData
+		}
+		d.r0 = int(binary.LittleEndian.Uint32(d.reader.input[d.reader.cursor:]))
+		d.r1 = int(binary.LittleEndian.Uint32(d.reader.input[d.reader.cursor+4:]))
+		d.r2 = int(binary.LittleEndian.Uint32(d.reader.input[d.reader.cursor+8:]))
+		d.reader.cursor += 12
+	default:
+		return ErrInvalidData
+	}
+	return nil
+}
+
+// decodeRun 在当前块中解码n个字节, 匹配不能跨越块或帧的边界
+func (d *Decompressor) decodeRun(n int) error {
+	end := len(d.__output) + n
+
+	if d.blockType == BLOCKTYPE_UNCOMPRESSED {
+		if d.reader.cursor+n > len(d.reader.input) {
+			return ErrInvalidData
+		}
+		d.__output = append(d.__output, d.reader.input[d.reader.cursor:d.reader.cursor+n]...)
+		d.reader.cursor += n
+		return nil
+	}
+
+	for len(d.__output) < end {
+		symbol, err := d.mainTree.decode(&d.reader)
+		if err != nil {
+			return err
+		}
+		if symbol < NUM_CHARS {
+			d.__output = append(d.__output, byte(symbol))
+			continue
+		}
+
+		symbol -= NUM_CHARS
+		length := symbol & 7
+		slot := symbol >> 3
+		if length == 7 {
+			footer, err := d.lengthTree.decode(&d.reader)
+			if err != nil {
+				return err
+			}
+			length += footer
+		}
+		length += MIN_MATCH
+
+		var offset int
+		switch slot {
+		case 0:
+			offset = d.r0
+		case 1:
+			offset = d.r1
+			d.r1 = d.r0
+			d.r0 = offset
+		case 2:
+			offset = d.r2
+			d.r2 = d.r0
+			d.r0 = offset
+		default:
+			extra := extraBits[slot]
+			offset = positionBase[slot] - 2
+			if d.blockType == BLOCKTYPE_ALIGNED && extra >= 3 {
+				offset += d.reader.readBits(extra-3) << 3
+				aligned, err := d.alignedTree.decode(&d.reader)
+				if err != nil {
+					return err
+				}
+				offset += aligned
+			} else {
+				offset += d.reader.readBits(extra)
+			}
+			d.r2 = d.r1
+			d.r1 = d.r0
+			d.r0 = offset
+		}
+
+		if offset <= 0 || offset > len(d.__output) || len(d.__output)+length > end {
+			return ErrInvalidData
+		}
+		position := len(d.__output) - offset
+		for i := 0; i < length; i++ {
+			d.__output = append(d.__output, d.__output[position+i])
+		}
+	}
+	return nil
+}
+
+// undoE8 还原x86 CALL(E8)指令的地址转换
+func (d *Decompressor) undoE8(data []byte, position int) {
+	for i := 0; i < len(data)-10; i++ {
+		if data[i] != 0xE8 {
+			continue
+		}
+		current := position + i
+		absolute := int(int32(binary.LittleEndian.Uint32(data[i+1:])))
+		if absolute >= -current && absolute < d.e8FileSize {
+			relative := absolute - current
+			if absolute < 0 {
+				relative = absolute + d.e8FileSize
+			}
+			binary.LittleEndian.PutUint32(data[i+1:], uint32(relative))
+		}
+		i += 4
+	}
+}
+
+func (d *Decompressor) Decompress(outputSize int) ([]byte, error) {
+	if d.windowBits < MIN_WINDOW_BITS || d.windowBits > MAX_WINDOW_BITS {
+		return nil, ErrInvalidWindowSize
+	}
+
+	d.__output = make([]byte, 0, outputSize)
+
+	if d.wim {
+		d.e8FileSize = WIM_E8_FILESIZE
+	} else if d.reader.readBits(1) == 1 {
+		d.e8FileSize = d.reader.readBits(16)<<16 | d.reader.readBits(16)
+	}
+
+	// WIM格式的一个块为一帧, 中间不需要对齐
+	frameSize := FRAME_SIZE
+	if d.wim {
+		frameSize = outputSize
+	}
+
+	for frameStart := 0; frameStart < outputSize; frameStart += frameSize {
+		frameEnd := frameStart + frameSize
+		if frameEnd > outputSize {
+			frameEnd = outputSize
+		}
+
+		for len(d.__output) < frameEnd {
+			if d.blockRemain == 0 {
+				if err := d.readBlockHeader(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			n := frameEnd - len(d.__output)
+			if n > d.blockRemain {
+				n = d.blockRemain
+			}
+			if err := d.decodeRun(n); err != nil {
+				return nil, err
+			}
+			d.blockRemain -= n
+		}
+
+		if !d.wim {
+			d.reader.alignFrame()
+		}
+	}
+
+	if d.e8FileSize == 0 {
+		return d.__output, nil
+	}
+
+	// 窗口中保留转换后的数据, E8还原在副本上进行
+	result := append([]byte(nil), d.__output...)
+	for frameStart := 0; frameStart < len(result); frameStart += frameSize {
+		frameEnd := frameStart + frameSize
+		if frameEnd > len(result) {
+			frameEnd = len(result)
+		}
+		if frameEnd-frameStart > 10 && (d.wim || (d.Offset+frameStart)/FRAME_SIZE < 32768) {
+			d.undoE8(result[frameStart:frameEnd], d.Offset+frameStart)
+		}
+	}
+	return result, nil
+}
+
+func newDecompressor(input []byte, windowBits int, wim bool) *Decompressor {
+	d := &Decompressor{
+		reader:      bitReader{input: input},
+		wim:         wim,
+		windowBits:  windowBits,
+		pretree:     newHuffman(PRETREE_SYMBOLS),
+		lengthTree:  newHuffman(LENGTH_SYMBOLS),
+		alignedTree: newHuffman(ALIGNED_SYMBOLS),
+	}
+	if windowBits >= MIN_WINDOW_BITS && windowBits <= MAX_WINDOW_BITS {
+		d.mainSize = NUM_CHARS + 8*positionSlots[windowBits-MIN_WINDOW_BITS]
+	}
+	d.mainTree = newHuffman(d.mainSize)
+	d.reset()
+	return d
+}
+
+// NewDecompressor CAB/CHM格式: 开头有E8转换头, 块大小为24位, 每32KB输出对齐一次
+func NewDecompressor(input []byte, windowBits int) *Decompressor {
+	return newDecompressor(input, windowBits, false)
+}
+
+// NewWIMDecompressor WIM/WOF格式: 没有E8转换头(固定为12000000), 块大小带有默认值标志位
+func NewWIMDecompressor(input []byte, windowBits int) *Decompressor {
+	return newDecompressor(input, windowBits, true)
+}
@@ -0,0 +1,69 @@
+package lzx
+
+import "fmt"
+
+const (
+	MIN_WINDOW_BITS = 15
+	MAX_WINDOW_BITS = 21
+
+	FRAME_SIZE = 0x8000
+	NUM_CHARS  = 256
+	MIN_MATCH  = 2
+	MAX_MATCH  = 257
+
+	PRETREE_SYMBOLS = 20
+	LENGTH_SYMBOLS  = 249
+	ALIGNED_SYMBOLS = 8
+	MAX_CODE_BITS   = 16
+
+	BLOCKTYPE_VERBATIM     = 1
+	BLOCKTYPE_ALIGNED      = 2
+	BLOCKTYPE_UNCOMPRESSED = 3
+
+	// WIM_E8_FILESIZE WIM/WOF固定使用的E8转换大小
+	WIM_E8_FILESIZE = 12000000
+	// WIM_DEFAULT_BLOCK_SIZE WIM块头中默认大小标志位对应的块大小
+	WIM_DEFAULT_BLOCK_SIZE = 0x8000
+)
+
+var (
+	ErrInvalidData       = fmt.Errorf("the input data is invalid")
+	ErrInvalidWindowSize = fmt.Errorf("the LZX window size is invalid")
+	ErrInvalidTable      = fmt.Errorf("the huffman table is invalid")
+)
+
+var (
+	// positionSlots 不同窗口大小(2^15 ~ 2^21)对应的position slot数量
+	positionSlots = [...]int{30, 32, 34, 36, 38, 42, 50}
+
+	extraBits    [50]int
+	positionBase [51]int
+)
+
+func init() {
+	for i := range extraBits {
+		switch {
+		case i < 4:
+			extraBits[i] = 0
+		case i >= 36:
+			extraBits[i] = 17
+		default:
+			extraBits[i] = i/2 - 1
+		}
+		positionBase[i+1] = positionBase[i] + 1<<extraBits[i]
+	}
+}
+
+// Decompress 解压CAB/CHM格式的LZX数据流
+func Decompress(input []byte, windowBits int, outputSize int) ([]byte, error) {
+	return NewDecompressor(input, windowBits).Decompress(outputSize)
+}
+
+// DecompressWIM 解压WIM/WOF格式的一个LZX块(chunk), 每个块都是独立的
+func DecompressWIM(input []byte, outputSize int) ([]byte, error) {
+	windowBits := MIN_WINDOW_BITS
+	for 1<<windowBits < outputSize && windowBits < MAX_WINDOW_BITS {
+		windowBits++
+	}
+	return NewWIMDecompressor(input, windowBits).Decompress(outputSize)
+}
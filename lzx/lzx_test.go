@@ -0,0 +1,199 @@
+package lzx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// bitWriter 与bitReader对应: 按16位小端字写入, 每个字从最高位开始
+type bitWriter struct {
+	output []byte
+	word   uint16
+	count  int
+}
+
+func (w *bitWriter) writeBits(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.word = w.word<<1 | uint16(value>>uint(i)&1)
+		w.count++
+		if w.count == 16 {
+			w.output = append(w.output, byte(w.word), byte(w.word>>8))
+			w.word, w.count = 0, 0
+		}
+	}
+}
+
+// align 补齐当前16位字, 已经对齐时补一个完整的字(未压缩块头的要求)
+func (w *bitWriter) align() {
+	w.writeBits(0, 16-w.count)
+}
+
+func (w *bitWriter) flush() []byte {
+	if w.count != 0 {
+		w.writeBits(0, 16-w.count)
+	}
+	return w.output
+}
+
+// canonical 与huffman.build相同的规则计算每个符号的编码
+func canonical(lengths []uint8) []int {
+	codes := make([]int, len(lengths))
+	code := 0
+	for length := 1; length <= MAX_CODE_BITS; length++ {
+		for symbol, l := range lengths {
+			if int(l) == length {
+				codes[symbol] = code
+				code++
+			}
+		}
+		code <<= 1
+	}
+	return codes
+}
+
+// writeLengths 预树的20个符号都使用5位编码, 之前的码长都为0, 所以直接写 (17-length)%17
+func (w *bitWriter) writeLengths(lengths []uint8) {
+	for i := 0; i < PRETREE_SYMBOLS; i++ {
+		w.writeBits(5, 4)
+	}
+	for _, l := range lengths {
+		w.writeBits((17-int(l))%17, 5)
+	}
+}
+
+func (w *bitWriter) writeBlockHeader(wim bool, blockType, blockSize int) {
+	w.writeBits(blockType, 3)
+	if wim {
+		w.writeBits(0, 1)
+		w.writeBits(blockSize, 16)
+	} else {
+		w.writeBits(blockSize>>8, 16)
+		w.writeBits(blockSize&0xFF, 8)
+	}
+}
+
+type token struct {
+	literal byte
+	// mainSymbol 非0时为匹配的主树符号
+	mainSymbol int
+	extra      int
+	extraBits  int
+}
+
+// verbatimStream 构造只有一个verbatim块的数据流(窗口为2^15)
+func verbatimStream(wim bool, size int, tokens []token) []byte {
+	mainTree := make([]uint8, NUM_CHARS+8*positionSlots[0])
+	for _, t := range tokens {
+		if t.mainSymbol != 0 {
+			mainTree[t.mainSymbol] = 4
+		} else {
+			mainTree[t.literal] = 4
+		}
+	}
+	codes := canonical(mainTree)
+
+	w := &bitWriter{}
+	if !wim {
+		// 没有E8转换
+		w.writeBits(0, 1)
+	}
+	w.writeBlockHeader(wim, BLOCKTYPE_VERBATIM, size)
+	w.writeLengths(mainTree[:NUM_CHARS])
+	w.writeLengths(mainTree[NUM_CHARS:])
+	w.writeLengths(make([]uint8, LENGTH_SYMBOLS))
+	for _, t := range tokens {
+		if t.mainSymbol != 0 {
+			w.writeBits(codes[t.mainSymbol], 4)
+			w.writeBits(t.extra, t.extraBits)
+		} else {
+			w.writeBits(codes[t.literal], 4)
+		}
+	}
+	return w.flush()
+}
+
+// abcTokens "abc" + 偏移3长度6的匹配 + 重复偏移(r0)长度6的匹配 -> "abc" * 5
+func abcTokens() []token {
+	return []token{
+		{literal: 'a'},
+		{literal: 'b'},
+		{literal: 'c'},
+		// 偏移3: 格式化偏移5 = slot 4(base 4) + 1个额外位(1), 长度6: 长度头4
+		{mainSymbol: NUM_CHARS + 4<<3 | 4, extra: 1, extraBits: 1},
+		// slot 0: r0
+		{mainSymbol: NUM_CHARS + 0<<3 | 4},
+	}
+}
+
+func TestDecompressVerbatim(t *testing.T) {
+	expected := bytes.Repeat([]byte("abc"), 5)
+
+	for _, wim := range []bool{false, true} {
+		stream := verbatimStream(wim, len(expected), abcTokens())
+
+		var output []byte
+		var err error
+		if wim {
+			output, err = DecompressWIM(stream, len(expected))
+		} else {
+			output, err = Decompress(stream, MIN_WINDOW_BITS, len(expected))
+		}
+		if err != nil {
+			t.Fatalf("wim=%v: %v", wim, err)
+		}
+		if !bytes.Equal(output, expected) {
+			t.Fatalf("wim=%v: output = %q, expected %q", wim, output, expected)
+		}
+	}
+}
+
+func TestDecompressWIMUncompressedE8(t *testing.T) {
+	// 位置1的CALL, 绝对地址16 -> 相对地址15
+	data := []byte{0x90, 0xE8, 0x10, 0x00, 0x00, 0x00, 0x90, 0x90, 0x90, 0x90, 0x90, 0x90, 0x90, 0x90, 0x90, 0x90, 0x90}
+
+	w := &bitWriter{}
+	w.writeBlockHeader(true, BLOCKTYPE_UNCOMPRESSED, len(data))
+	w.align()
+	// r0, r1, r2
+	repeats := make([]byte, 12)
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint32(repeats[4*i:], 1)
+	}
+	stream := append(append(w.flush(), repeats...), data...)
+
+	output, err := DecompressWIM(stream, len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := append([]byte(nil), data...)
+	expected[2] = 0x0F
+	if !bytes.Equal(output, expected) {
+		t.Fatalf("output = % x, expected % x", output, expected)
+	}
+}
+
+func TestDecompressInvalid(t *testing.T) {
+	// 第一个符号就是匹配, 偏移超出已解压的数据
+	tokens := abcTokens()[3:]
+	if _, err := DecompressWIM(verbatimStream(true, 6, tokens), 6); err != ErrInvalidData {
+		t.Errorf("match before start: err = %v, expected ErrInvalidData", err)
+	}
+
+	// 匹配超出块的结尾
+	if _, err := DecompressWIM(verbatimStream(true, 5, abcTokens()), 15); err != ErrInvalidData {
+		t.Errorf("match past block: err = %v, expected ErrInvalidData", err)
+	}
+
+	// 被截断的未压缩块
+	w := &bitWriter{}
+	w.writeBlockHeader(true, BLOCKTYPE_UNCOMPRESSED, 100)
+	w.align()
+	if _, err := DecompressWIM(append(w.flush(), make([]byte, 20)...), 100); err != ErrInvalidData {
+		t.Errorf("truncated block: err = %v, expected ErrInvalidData", err)
+	}
+
+	if _, err := Decompress(nil, MAX_WINDOW_BITS+1, 10); err != ErrInvalidWindowSize {
+		t.Errorf("window size: err = %v, expected ErrInvalidWindowSize", err)
+	}
+}
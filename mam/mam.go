@@ -0,0 +1,138 @@
+package mam
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/wabzsy/compression/xpress"
+	"github.com/wabzsy/compression/xpresshuff"
+)
+
+// Windows 8.1+ 的预取文件(.pf)以及部分系统文件使用的MAM容器:
+//   0: "MAM"
+//   3: 低4位为压缩格式(COMPRESSION_FORMAT_*), 高4位不为0时表示有CRC32
+//   4: 解压后的大小
+//   8: CRC32(可选), 计算时该字段按0处理, 范围为整个文件
+
+const (
+	COMPRESSION_FORMAT_XPRESS      = 3
+	COMPRESSION_FORMAT_XPRESS_HUFF = 4
+
+	FLAG_CRC = 0x80
+)
+
+var (
+	Magic = [3]byte{'M', 'A', 'M'}
+)
+
+var (
+	ErrInvalidHeader     = fmt.Errorf("the MAM header is invalid")
+	ErrChecksumMismatch  = fmt.Errorf("the MAM checksum does not match")
+	ErrUnsupportedFormat = fmt.Errorf("the MAM compression format is not supported")
+	ErrSizeMismatch      = fmt.Errorf("the decompressed size does not match")
+)
+
+type Header struct {
+	Format           uint8
+	HasCRC           bool
+	UncompressedSize uint32
+	CRC              uint32
+}
+
+func (h *Header) Size() int {
+	if h.HasCRC {
+		return 12
+	}
+	return 8
+}
+
+func (h *Header) Bytes() []byte {
+	buf := make([]byte, h.Size())
+	copy(buf, Magic[:])
+	buf[3] = h.Format & 0x0F
+	if h.HasCRC {
+		buf[3] |= FLAG_CRC
+		binary.LittleEndian.PutUint32(buf[8:], h.CRC)
+	}
+	binary.LittleEndian.PutUint32(buf[4:], h.UncompressedSize)
+	return buf
+}
+
+func ParseHeader(input []byte) (*Header, error) {
+	if len(input) < 8 || input[0] != Magic[0] || input[1] != Magic[1] || input[2] != Magic[2] {
+		return nil, ErrInvalidHeader
+	}
+
+	header := &Header{
+		Format:           input[3] & 0x0F,
+		HasCRC:           input[3]&0xF0 != 0,
+		UncompressedSize: binary.LittleEndian.Uint32(input[4:]),
+	}
+	if header.HasCRC {
+		if len(input) < 12 {
+			return nil, ErrInvalidHeader
+		}
+		header.CRC = binary.LittleEndian.Uint32(input[8:])
+	}
+	return header, nil
+}
+
+func checksum(input []byte) uint32 {
+	crc := crc32.Update(0, crc32.IEEETable, input[:8])
+	crc = crc32.Update(crc, crc32.IEEETable, []byte{0, 0, 0, 0})
+	return crc32.Update(crc, crc32.IEEETable, input[12:])
+}
+
+func Decompress(input []byte) ([]byte, error) {
+	header, err := ParseHeader(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.HasCRC && checksum(input) != header.CRC {
+		return nil, ErrChecksumMismatch
+	}
+
+	payload := input[header.Size():]
+	var output []byte
+	switch header.Format {
+	case COMPRESSION_FORMAT_XPRESS_HUFF:
+		output, err = xpresshuff.Decompress(payload, int(header.UncompressedSize))
+	case COMPRESSION_FORMAT_XPRESS:
+		output, err = xpress.Decompress(payload)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(output) != int(header.UncompressedSize) {
+		return nil, ErrSizeMismatch
+	}
+	return output, nil
+}
+
+// CompressWithCRC 使用XPRESS Huffman压缩, 生成MAM\x04或MAM\x84
+func CompressWithCRC(input []byte, withCRC bool) ([]byte, error) {
+	payload, err := xpresshuff.Compress(input)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &Header{
+		Format:           COMPRESSION_FORMAT_XPRESS_HUFF,
+		HasCRC:           withCRC,
+		UncompressedSize: uint32(len(input)),
+	}
+	output := append(header.Bytes(), payload...)
+	if withCRC {
+		binary.LittleEndian.PutUint32(output[8:], checksum(output))
+	}
+	return output, nil
+}
+
+func Compress(input []byte) ([]byte, error) {
+	return CompressWithCRC(input, false)
+}
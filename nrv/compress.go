@@ -0,0 +1,360 @@
+package nrv
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	MAX_OFFSET = 0x100000
+	MAX_CHAIN  = 256
+	NICE_LEN   = 256
+	HASH_BITS  = 16
+)
+
+type Compressor struct {
+	method Method
+
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+	__lastOffset  int
+
+	bitBuffer uint32
+	bitCount  int
+	bitCursor int
+
+	head []int
+	prev []int
+}
+
+func (c *Compressor) putBit(bit int) {
+	if c.bitCount == c.method.BitSize() {
+		c.flushBits()
+	}
+	if c.bitCount == 0 {
+		// 为bit buffer预留位置, 解压时会在第一次取bit时读取
+		c.bitCursor = len(c.__output)
+		c.__output = append(c.__output, make([]byte, c.method.BitSize()/8)...)
+		c.bitBuffer = 0
+	}
+	c.bitBuffer = c.bitBuffer<<1 | uint32(bit&1)
+	c.bitCount++
+}
+
+func (c *Compressor) flushBits() {
+	if c.bitCount == 0 {
+		return
+	}
+	value := c.bitBuffer << (c.method.BitSize() - c.bitCount)
+	switch c.method.BitSize() {
+	case 8:
+		c.__output[c.bitCursor] = byte(value)
+	case 16:
+		binary.LittleEndian.PutUint16(c.__output[c.bitCursor:], uint16(value))
+	case 32:
+		binary.LittleEndian.PutUint32(c.__output[c.bitCursor:], value)
+	}
+	c.bitCount = 0
+}
+
+func (c *Compressor) putByte(b byte) {
+	c.__output = append(c.__output, b)
+}
+
+// putGamma 写入 value>=2 的gamma编码, 与Decompressor.getGamma对应
+func (c *Compressor) putGamma(value uint32) {
+	n := bits.Len32(value) - 1
+	for i := n - 1; i >= 0; i-- {
+		c.putBit(int(value>>i) & 1)
+		if i == 0 {
+			c.putBit(1)
+		} else {
+			c.putBit(0)
+		}
+	}
+}
+
+// putGamma12 写入NRV2D/NRV2E使用的offset前缀编码, 与Decompressor中的循环对应
+func (c *Compressor) putGamma12(value uint32) {
+	// 从结果反推每一轮的bit
+	var sequence []int
+	m := value
+	sequence = append(sequence, 1, int(m&1))
+	m >>= 1
+	for m != 1 {
+		sequence = append(sequence, int(m&1), 0)
+		m = (m >> 1) + 1
+		sequence = append(sequence, int(m&1))
+		m >>= 1
+	}
+
+	for i := len(sequence) - 1; i >= 0; i-- {
+		c.putBit(sequence[i])
+	}
+}
+
+func gammaBits(value uint32) int {
+	return 2 * (bits.Len32(value) - 1)
+}
+
+func gamma12Bits(value uint32) int {
+	count := 2
+	for m := value >> 1; m != 1; m = ((m >> 1) + 1) >> 1 {
+		count += 3
+	}
+	return count
+}
+
+// matchBits 估算一个匹配需要占用的bit数, 返回-1表示无法编码
+func (c *Compressor) matchBits(offset, length int) int {
+	rest := length - 1
+	if offset > c.method.MaxNearOffset() {
+		rest--
+	}
+	if rest < 1 {
+		return -1
+	}
+
+	count := 1
+	if offset == c.__lastOffset {
+		count += 2
+		if c.method.Algorithm() != ALGORITHM_NRV2B {
+			count++
+		}
+	} else if c.method.Algorithm() == ALGORITHM_NRV2B {
+		count += gammaBits(uint32((offset-1)>>8)+3) + 8
+	} else {
+		count += gamma12Bits(uint32((offset-1)>>7)+3) + 8
+	}
+
+	switch c.method.Algorithm() {
+	case ALGORITHM_NRV2B:
+		if rest <= 3 {
+			count += 2
+		} else {
+			count += 2 + gammaBits(uint32(rest-2))
+		}
+	case ALGORITHM_NRV2D:
+		if rest <= 3 {
+			count += 1
+		} else {
+			count += 1 + gammaBits(uint32(rest-2))
+		}
+	case ALGORITHM_NRV2E:
+		if rest <= 2 {
+			count += 1
+		} else if rest <= 4 {
+			count += 2
+		} else {
+			count += 1 + gammaBits(uint32(rest-3))
+		}
+	}
+
+	return count
+}
+
+func (c *Compressor) codeLiteral() {
+	c.putBit(1)
+	c.putByte(c.__input[c.__inputCursor])
+	c.__inputCursor++
+}
+
+func (c *Compressor) codeMatch(offset, length int) {
+	rest := length - 1
+	if offset > c.method.MaxNearOffset() {
+		rest--
+	}
+
+	// 对于NRV2D/NRV2E, 长度的最高位存放在offset的最低位中
+	first := 0
+	switch c.method.Algorithm() {
+	case ALGORITHM_NRV2D:
+		if rest <= 3 {
+			first = rest >> 1
+		}
+	case ALGORITHM_NRV2E:
+		if rest <= 2 {
+			first = 1
+		}
+	}
+
+	c.putBit(0)
+
+	if offset == c.__lastOffset {
+		c.putBit(0)
+		c.putBit(1)
+		if c.method.Algorithm() != ALGORITHM_NRV2B {
+			c.putBit(first)
+		}
+	} else if c.method.Algorithm() == ALGORITHM_NRV2B {
+		c.putGamma(uint32((offset-1)>>8) + 3)
+		c.putByte(byte(offset - 1))
+	} else {
+		raw := (offset-1)<<1 | (first ^ 1)
+		c.putGamma12(uint32(raw>>8) + 3)
+		c.putByte(byte(raw))
+	}
+
+	switch c.method.Algorithm() {
+	case ALGORITHM_NRV2B:
+		if rest <= 3 {
+			c.putBit(rest >> 1)
+			c.putBit(rest & 1)
+		} else {
+			c.putBit(0)
+			c.putBit(0)
+			c.putGamma(uint32(rest - 2))
+		}
+	case ALGORITHM_NRV2D:
+		if rest <= 3 {
+			c.putBit(rest & 1)
+		} else {
+			c.putBit(0)
+			c.putGamma(uint32(rest - 2))
+		}
+	case ALGORITHM_NRV2E:
+		if rest <= 2 {
+			c.putBit(rest - 1)
+		} else if rest <= 4 {
+			c.putBit(1)
+			c.putBit(rest - 3)
+		} else {
+			c.putBit(0)
+			c.putGamma(uint32(rest - 3))
+		}
+	}
+
+	c.__inputCursor += length
+	c.__lastOffset = offset
+}
+
+func (c *Compressor) codeEnd() {
+	c.putBit(0)
+	if c.method.Algorithm() == ALGORITHM_NRV2B {
+		c.putGamma(0x1000002)
+	} else {
+		c.putGamma12(0x1000002)
+	}
+	c.putByte(0xFF)
+	c.flushBits()
+}
+
+func (c *Compressor) hash(position int) int {
+	v := uint32(c.__input[position])<<16 | uint32(c.__input[position+1])<<8 | uint32(c.__input[position+2])
+	return int((v * 2654435761) >> (32 - HASH_BITS))
+}
+
+func (c *Compressor) insert(position int) {
+	if position+2 < len(c.__input) {
+		h := c.hash(position)
+		c.prev[position] = c.head[h]
+		c.head[h] = position
+	}
+}
+
+func (c *Compressor) matchLength(position, offset int) int {
+	length := 0
+	for position+length < len(c.__input) && c.__input[position+length] == c.__input[position+length-offset] {
+		length++
+	}
+	return length
+}
+
+// find 返回position处收益最高的匹配
+func (c *Compressor) find(position int) (offset, length int) {
+	bestSaving := 0
+
+	check := func(o, l int) {
+		if cost := c.matchBits(o, l); cost > 0 {
+			if saving := 9*l - cost; saving > bestSaving {
+				bestSaving = saving
+				offset, length = o, l
+			}
+		}
+	}
+
+	// 优先检查last offset, 其编码代价最小
+	if c.__lastOffset <= position {
+		if l := c.matchLength(position, c.__lastOffset); l >= 2 {
+			check(c.__lastOffset, l)
+		}
+	}
+
+	if position+2 >= len(c.__input) {
+		return
+	}
+
+	chain := MAX_CHAIN
+	for candidate := c.head[c.hash(position)]; candidate >= 0 && chain > 0; candidate = c.prev[candidate] {
+		o := position - candidate
+		if o > MAX_OFFSET {
+			break
+		}
+		if l := c.matchLength(position, o); l >= 2 && (l > length || o < offset) {
+			check(o, l)
+			if length >= NICE_LEN {
+				break
+			}
+		}
+		chain--
+	}
+
+	return
+}
+
+func (c *Compressor) Compress() ([]byte, error) {
+	if c.method.Algorithm() == 0 || c.method.BitSize() == 0 {
+		return nil, ErrUnknownMethod
+	}
+
+	c.__output = make([]byte, 0, len(c.__input)/2+64)
+	c.head = make([]int, 1<<HASH_BITS)
+	for i := range c.head {
+		c.head[i] = -1
+	}
+	c.prev = make([]int, len(c.__input))
+
+	for c.__inputCursor < len(c.__input) {
+		offset, length := c.find(c.__inputCursor)
+
+		if length > 0 && length < NICE_LEN && c.__inputCursor+1 < len(c.__input) {
+			// lazy matching: 下一个位置有更长的匹配时, 先输出一个字面量
+			c.insert(c.__inputCursor)
+			nextOffset, nextLength := c.find(c.__inputCursor + 1)
+			if nextLength > length+1 || (nextLength > length && nextOffset < offset) {
+				c.codeLiteral()
+				continue
+			}
+			// 已插入当前位置, 后面从下一个位置开始插入
+			start := c.__inputCursor + 1
+			c.codeMatch(offset, length)
+			for i := start; i < c.__inputCursor; i++ {
+				c.insert(i)
+			}
+			continue
+		}
+
+		start := c.__inputCursor
+		if length > 0 {
+			c.codeMatch(offset, length)
+		} else {
+			c.codeLiteral()
+		}
+		for i := start; i < c.__inputCursor; i++ {
+			c.insert(i)
+		}
+	}
+
+	c.codeEnd()
+
+	return c.__output, nil
+}
+
+func NewCompressor(input []byte, method Method) *Compressor {
+	return &Compressor{
+		method:       method,
+		__input:      input,
+		__lastOffset: 1,
+	}
+}
@@ -0,0 +1,174 @@
+package nrv
+
+import (
+	"encoding/binary"
+)
+
+type Decompressor struct {
+	method Method
+
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+	bitBuffer     uint32
+	bitCount      int
+}
+
+func (d *Decompressor) mustReadByte() uint8 {
+	if d.__inputCursor >= len(d.__input) {
+		panic(ErrInvalidData)
+	}
+	d.__inputCursor++
+	return d.__input[d.__inputCursor-1]
+}
+
+func (d *Decompressor) getBit() uint32 {
+	if d.bitCount == 0 {
+		// load next bit buffer
+		size := d.method.BitSize() / 8
+		if d.__inputCursor+size > len(d.__input) {
+			panic(ErrInvalidData)
+		}
+		switch size {
+		case 1:
+			d.bitBuffer = uint32(d.__input[d.__inputCursor])
+		case 2:
+			d.bitBuffer = uint32(binary.LittleEndian.Uint16(d.__input[d.__inputCursor:]))
+		case 4:
+			d.bitBuffer = binary.LittleEndian.Uint32(d.__input[d.__inputCursor:])
+		}
+		d.__inputCursor += size
+		d.bitCount = d.method.BitSize()
+	}
+
+	d.bitCount--
+	return (d.bitBuffer >> d.bitCount) & 1
+}
+
+// getGamma 读取 1 x 1 x ... 形式的gamma编码(每个数据位后跟一个结束标记位)
+func (d *Decompressor) getGamma(value uint32) uint32 {
+	for {
+		value = value*2 + d.getBit()
+		if d.getBit() == 1 {
+			return value
+		}
+		if value >= 1<<30 {
+			panic(ErrInvalidData)
+		}
+	}
+}
+
+func (d *Decompressor) copyMatch(offset, length uint32) {
+	if offset == 0 || int(offset) > len(d.__output) {
+		panic(ErrInvalidData)
+	}
+	position := len(d.__output) - int(offset)
+	for i := 0; i < int(length); i++ {
+		d.__output = append(d.__output, d.__output[position+i])
+	}
+}
+
+func (d *Decompressor) dePack() {
+	lastOffset := uint32(1)
+	nearOffset := uint32(d.method.MaxNearOffset())
+	algorithm := d.method.Algorithm()
+
+	for {
+		for d.getBit() == 1 {
+			d.__output = append(d.__output, d.mustReadByte())
+		}
+
+		var offset, length uint32
+
+		if algorithm == ALGORITHM_NRV2B {
+			offset = d.getGamma(1)
+		} else {
+			offset = 1
+			for {
+				offset = offset*2 + d.getBit()
+				if d.getBit() == 1 {
+					break
+				}
+				offset = (offset-1)*2 + d.getBit()
+				if offset >= 1<<30 {
+					panic(ErrInvalidData)
+				}
+			}
+		}
+
+		if offset == 2 {
+			offset = lastOffset
+			if algorithm != ALGORITHM_NRV2B {
+				length = d.getBit()
+			}
+		} else {
+			offset = (offset-3)*256 + uint32(d.mustReadByte())
+			if offset == 0xFFFFFFFF {
+				// end marker
+				return
+			}
+			if algorithm != ALGORITHM_NRV2B {
+				length = (offset ^ 0xFFFFFFFF) & 1
+				offset >>= 1
+			}
+			offset++
+			lastOffset = offset
+		}
+
+		switch algorithm {
+		case ALGORITHM_NRV2B:
+			length = d.getBit()
+			length = length*2 + d.getBit()
+			if length == 0 {
+				length = d.getGamma(1) + 2
+			}
+		case ALGORITHM_NRV2D:
+			length = length*2 + d.getBit()
+			if length == 0 {
+				length = d.getGamma(1) + 2
+			}
+		case ALGORITHM_NRV2E:
+			if length != 0 {
+				length = 1 + d.getBit()
+			} else if d.getBit() == 1 {
+				length = 3 + d.getBit()
+			} else {
+				length = d.getGamma(1) + 3
+			}
+		}
+
+		if offset > nearOffset {
+			length++
+		}
+
+		d.copyMatch(offset, length+1)
+	}
+}
+
+func (d *Decompressor) Decompress() (result []byte, err error) {
+	if d.method.Algorithm() == 0 || d.method.BitSize() == 0 {
+		return nil, ErrUnknownMethod
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok && e == ErrInvalidData {
+				result, err = nil, e
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	d.__output = make([]byte, 0, len(d.__input)*2)
+	d.dePack()
+
+	return d.__output, nil
+}
+
+func NewDecompressor(input []byte, method Method) *Decompressor {
+	return &Decompressor{
+		method:  method,
+		__input: input,
+	}
+}
@@ -0,0 +1,81 @@
+package nrv
+
+import "fmt"
+
+type Method uint8
+
+// 与UPX中的压缩方法编号保持一致(M_NRV2B_LE32 等)
+const (
+	NRV2B_LE32 Method = 2
+	NRV2B_8    Method = 3
+	NRV2B_LE16 Method = 4
+	NRV2D_LE32 Method = 5
+	NRV2D_8    Method = 6
+	NRV2D_LE16 Method = 7
+	NRV2E_LE32 Method = 8
+	NRV2E_8    Method = 9
+	NRV2E_LE16 Method = 10
+)
+
+const (
+	ALGORITHM_NRV2B = 'B'
+	ALGORITHM_NRV2D = 'D'
+	ALGORITHM_NRV2E = 'E'
+)
+
+var (
+	ErrInvalidData   = fmt.Errorf("the input data is invalid")
+	ErrUnknownMethod = fmt.Errorf("unknown NRV method")
+)
+
+// Algorithm 返回压缩算法: ALGORITHM_NRV2B / ALGORITHM_NRV2D / ALGORITHM_NRV2E
+func (m Method) Algorithm() int {
+	switch m {
+	case NRV2B_LE32, NRV2B_8, NRV2B_LE16:
+		return ALGORITHM_NRV2B
+	case NRV2D_LE32, NRV2D_8, NRV2D_LE16:
+		return ALGORITHM_NRV2D
+	case NRV2E_LE32, NRV2E_8, NRV2E_LE16:
+		return ALGORITHM_NRV2E
+	}
+	return 0
+}
+
+// BitSize 返回bit buffer的宽度: 8 / 16 / 32
+func (m Method) BitSize() int {
+	switch m {
+	case NRV2B_8, NRV2D_8, NRV2E_8:
+		return 8
+	case NRV2B_LE16, NRV2D_LE16, NRV2E_LE16:
+		return 16
+	case NRV2B_LE32, NRV2D_LE32, NRV2E_LE32:
+		return 32
+	}
+	return 0
+}
+
+// MaxNearOffset 超过该偏移量的匹配, 长度编码时会额外减1 (M2_MAX_OFFSET)
+func (m Method) MaxNearOffset() int {
+	if m.Algorithm() == ALGORITHM_NRV2B {
+		return 0xD00
+	}
+	return 0x500
+}
+
+func (m Method) String() string {
+	if m.Algorithm() == 0 {
+		return fmt.Sprintf("Method(%d)", uint8(m))
+	}
+	if m.BitSize() == 8 {
+		return fmt.Sprintf("NRV2%c_8", m.Algorithm())
+	}
+	return fmt.Sprintf("NRV2%c_LE%d", m.Algorithm(), m.BitSize())
+}
+
+func Decompress(input []byte, method Method) ([]byte, error) {
+	return NewDecompressor(input, method).Decompress()
+}
+
+func Compress(input []byte, method Method) ([]byte, error) {
+	return NewCompressor(input, method).Compress()
+}
@@ -0,0 +1,84 @@
+package nrv
+
+import (
+	"bytes"
+	"testing"
+)
+
+var methods = []Method{
+	NRV2B_LE32, NRV2B_8, NRV2B_LE16,
+	NRV2D_LE32, NRV2D_8, NRV2D_LE16,
+	NRV2E_LE32, NRV2E_8, NRV2E_LE16,
+}
+
+// testData 可压缩的文本, 中间夹着伪随机数据, 使近/远偏移和各种长度的匹配都会出现
+func testData(size int) []byte {
+	data := make([]byte, size)
+	seed := uint32(1)
+	for i := range data {
+		if i%5000 < 3000 {
+			data[i] = "the quick brown fox jumps over the lazy dog "[i%44]
+		} else {
+			seed = seed*1103515245 + 12345
+			data[i] = byte(seed >> 16)
+		}
+	}
+	return data
+}
+
+func TestRoundTrip(t *testing.T) {
+	inputs := [][]byte{
+		{},
+		{'x'},
+		[]byte("abcabcabcabcabcabc"),
+		bytes.Repeat([]byte{0}, 70000),
+		testData(100000),
+	}
+	for _, method := range methods {
+		for _, input := range inputs {
+			compressed, err := Compress(input, method)
+			if err != nil {
+				t.Fatalf("%v, length %d: %v", method, len(input), err)
+			}
+			output, err := Decompress(compressed, method)
+			if err != nil {
+				t.Fatalf("%v, length %d: %v", method, len(input), err)
+			}
+			if !bytes.Equal(output, input) {
+				t.Fatalf("%v, length %d: round-trip mismatch", method, len(input))
+			}
+		}
+	}
+}
+
+func TestDecompressInvalid(t *testing.T) {
+	input := testData(20000)
+	for _, method := range methods {
+		compressed, err := Compress(input, method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 截断的数据流
+		for _, length := range []int{0, 1, len(compressed) / 2, len(compressed) - 1} {
+			if _, err = Decompress(compressed[:length], method); err != ErrInvalidData {
+				t.Errorf("%v, truncated to %d: err = %v, expected ErrInvalidData", method, length, err)
+			}
+		}
+	}
+
+	// 第一个符号就是匹配
+	for _, method := range []Method{NRV2B_8, NRV2D_8, NRV2E_8} {
+		if _, err := Decompress([]byte{0x00, 0xFF, 0xFF, 0xFF}, method); err != ErrInvalidData {
+			t.Errorf("%v, match before any output: err = %v, expected ErrInvalidData", method, err)
+		}
+	}
+
+	for _, method := range []Method{0, 1, 11} {
+		if _, err := Decompress([]byte{0x80}, method); err != ErrUnknownMethod {
+			t.Errorf("%v: err = %v, expected ErrUnknownMethod", method, err)
+		}
+		if _, err := Compress([]byte{1}, method); err != ErrUnknownMethod {
+			t.Errorf("%v: err = %v, expected ErrUnknownMethod", method, err)
+		}
+	}
+}
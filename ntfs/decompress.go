@@ -0,0 +1,175 @@
+package ntfs
+
+import (
+	"io"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/wabzsy/compression/lznt1"
+)
+
+// ReaderAt 按压缩单元解压的io.ReaderAt, 缓存最近一次解压的单元
+type ReaderAt struct {
+	volume    io.ReaderAt
+	attribute *Attribute
+	unitSize  int64
+	// vcns 每个运行的起始VCN, 与attribute.Runs一一对应
+	vcns []int64
+
+	mutex      sync.Mutex
+	cachedUnit int64
+	cached     []byte
+}
+
+func NewReaderAt(volume io.ReaderAt, attribute *Attribute) (*ReaderAt, error) {
+	if attribute.ClusterSize <= 0 || attribute.ClusterSize&(attribute.ClusterSize-1) != 0 {
+		return nil, ErrInvalidCluster
+	}
+	if attribute.CompressionUnit < 0 || attribute.CompressionUnit > MAX_COMPRESSION_UNIT {
+		return nil, ErrInvalidUnit
+	}
+	if attribute.DataSize < 0 || attribute.AllocatedSize < 0 ||
+		(attribute.AllocatedSize != 0 && attribute.DataSize > attribute.AllocatedSize) ||
+		attribute.DataSize > math.MaxInt64-attribute.unitClusters()*int64(attribute.ClusterSize) {
+		return nil, ErrInvalidDataSize
+	}
+
+	r := &ReaderAt{
+		volume:     volume,
+		attribute:  attribute,
+		unitSize:   attribute.unitClusters() * int64(attribute.ClusterSize),
+		cachedUnit: -1,
+	}
+
+	// 只记录每个运行的起始VCN, 运行的总长度不能超过分配大小
+	clusterSize := int64(attribute.ClusterSize)
+	limit := attribute.allocatedClusters()
+	vcn := int64(0)
+	for _, run := range attribute.Runs {
+		if run.Length <= 0 || run.Length > limit-vcn {
+			return nil, ErrInvalidRunList
+		}
+		if !run.Sparse && (run.LCN < 0 || run.LCN > math.MaxInt64/clusterSize-run.Length) {
+			return nil, ErrInvalidRunList
+		}
+		r.vcns = append(r.vcns, vcn)
+		vcn += run.Length
+	}
+
+	return r, nil
+}
+
+func (r *ReaderAt) Size() int64 {
+	return r.attribute.DataSize
+}
+
+func (r *ReaderAt) lcn(vcn int64) int64 {
+	index := sort.Search(len(r.vcns), func(i int) bool {
+		return r.vcns[i] > vcn
+	}) - 1
+	// 运行列表之外的簇视为稀疏
+	if index < 0 || vcn-r.vcns[index] >= r.attribute.Runs[index].Length {
+		return -1
+	}
+	run := r.attribute.Runs[index]
+	if run.Sparse {
+		return -1
+	}
+	return run.LCN + vcn - r.vcns[index]
+}
+
+func (r *ReaderAt) readClusters(vcn, count int64) ([]byte, error) {
+	clusterSize := int64(r.attribute.ClusterSize)
+	raw := make([]byte, count*clusterSize)
+	for i := int64(0); i < count; i++ {
+		if _, err := r.volume.ReadAt(raw[i*clusterSize:(i+1)*clusterSize], r.lcn(vcn+i)*clusterSize); err != nil {
+			return nil, err
+		}
+	}
+	return raw, nil
+}
+
+// readUnit 读取第index个压缩单元:
+// 全部稀疏 -> 全0; 全部分配 -> 未压缩; 前N个分配其余稀疏 -> LZNT1压缩
+func (r *ReaderAt) readUnit(index int64) ([]byte, error) {
+	clusters := r.attribute.unitClusters()
+	start := index * clusters
+
+	allocated := int64(0)
+	for i := int64(0); i < clusters; i++ {
+		if r.lcn(start+i) >= 0 {
+			if allocated != i {
+				// 已分配的簇必须在单元的开头
+				return nil, ErrInvalidRunList
+			}
+			allocated++
+		}
+	}
+
+	switch allocated {
+	case 0:
+		return make([]byte, r.unitSize), nil
+	case clusters:
+		return r.readClusters(start, clusters)
+	}
+
+	raw, err := r.readClusters(start, allocated)
+	if err != nil {
+		return nil, err
+	}
+	return DecompressUnit(raw, int(r.unitSize))
+}
+
+func (r *ReaderAt) unit(index int64) ([]byte, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.cachedUnit == index {
+		return r.cached, nil
+	}
+
+	data, err := r.readUnit(index)
+	if err != nil {
+		return nil, err
+	}
+	r.cachedUnit, r.cached = index, data
+	return data, nil
+}
+
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrInvalidData
+	}
+	if off >= r.attribute.DataSize {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+
+	n := 0
+	for n < len(p) && off < r.attribute.DataSize {
+		data, err := r.unit(off / r.unitSize)
+		if err != nil {
+			return n, err
+		}
+		end := r.unitSize
+		if remain := r.attribute.DataSize - off/r.unitSize*r.unitSize; remain < end {
+			end = remain
+		}
+		copied := copy(p[n:], data[off%r.unitSize:end])
+		n += copied
+		off += int64(copied)
+	}
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// DecompressUnit 解压一个压缩单元, 块的处理与lznt1.Decompress相同, 单元的末尾以0填充
+func DecompressUnit(raw []byte, unitSize int) ([]byte, error) {
+	return lznt1.DecompressWithSize(raw, unitSize)
+}
@@ -0,0 +1,118 @@
+package ntfs
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// DEFAULT_COMPRESSION_UNIT 压缩单元为 1<<4 = 16 个簇
+	DEFAULT_COMPRESSION_UNIT = 4
+	// MAX_COMPRESSION_UNIT 允许的最大压缩单元(簇数的log2)
+	MAX_COMPRESSION_UNIT = 8
+)
+
+var (
+	ErrInvalidData     = fmt.Errorf("the input data is invalid")
+	ErrInvalidRunList  = fmt.Errorf("the data run list is invalid")
+	ErrInvalidCluster  = fmt.Errorf("the cluster size is invalid")
+	ErrInvalidDataSize = fmt.Errorf("the data size is invalid")
+	ErrInvalidUnit     = fmt.Errorf("the compression unit is invalid")
+)
+
+// DataRun 一段连续的簇, Sparse为true时LCN无意义, 内容全为0
+type DataRun struct {
+	LCN    int64
+	Length int64
+	Sparse bool
+}
+
+// Attribute 一个压缩的非常驻$DATA属性
+type Attribute struct {
+	Runs        []DataRun
+	ClusterSize int
+	// CompressionUnit 属性头中的压缩单元大小(簇数的log2), 为0时使用DEFAULT_COMPRESSION_UNIT
+	CompressionUnit int
+	// DataSize 属性头中的实际数据大小
+	DataSize int64
+	// AllocatedSize 属性头中的分配大小, 运行列表的总簇数不能超过它;
+	// 为0时使用DataSize向上取整到压缩单元的大小
+	AllocatedSize int64
+}
+
+func (a *Attribute) unitClusters() int64 {
+	if a.CompressionUnit == 0 {
+		return 1 << DEFAULT_COMPRESSION_UNIT
+	}
+	return 1 << a.CompressionUnit
+}
+
+// allocatedClusters 运行列表最多可以覆盖的簇数
+func (a *Attribute) allocatedClusters() int64 {
+	clusterSize := int64(a.ClusterSize)
+	if a.AllocatedSize != 0 {
+		return a.AllocatedSize / clusterSize
+	}
+	unitSize := a.unitClusters() * clusterSize
+	return (a.DataSize + unitSize - 1) / unitSize * a.unitClusters()
+}
+
+// ParseDataRuns 解析属性头中的mapping pairs, 以0x00结尾
+func ParseDataRuns(input []byte) ([]DataRun, error) {
+	var runs []DataRun
+	lcn := int64(0)
+
+	for cursor := 0; cursor < len(input) && input[cursor] != 0; {
+		lengthSize := int(input[cursor] & 0x0F)
+		offsetSize := int(input[cursor] >> 4)
+		cursor++
+
+		if lengthSize == 0 || lengthSize > 8 || offsetSize > 8 || cursor+lengthSize+offsetSize > len(input) {
+			return nil, ErrInvalidRunList
+		}
+
+		length := int64(0)
+		for i := lengthSize - 1; i >= 0; i-- {
+			length = length<<8 | int64(input[cursor+i])
+		}
+		cursor += lengthSize
+		if length <= 0 {
+			return nil, ErrInvalidRunList
+		}
+
+		// offset大小为0表示稀疏
+		if offsetSize == 0 {
+			runs = append(runs, DataRun{Length: length, Sparse: true})
+			continue
+		}
+
+		// offset是相对上一个LCN的有符号数
+		offset := int64(int8(input[cursor+offsetSize-1]))
+		for i := offsetSize - 2; i >= 0; i-- {
+			offset = offset<<8 | int64(input[cursor+i])
+		}
+		cursor += offsetSize
+
+		lcn += offset
+		if lcn < 0 {
+			return nil, ErrInvalidRunList
+		}
+		runs = append(runs, DataRun{LCN: lcn, Length: length})
+	}
+
+	return runs, nil
+}
+
+// Decompress 读取整个属性的内容, volume为整个卷(或从LCN 0开始的原始簇数据)
+func Decompress(volume io.ReaderAt, attribute *Attribute) ([]byte, error) {
+	reader, err := NewReaderAt(volume, attribute)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]byte, reader.Size())
+	if _, err = reader.ReadAt(output, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return output, nil
+}
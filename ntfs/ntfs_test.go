@@ -0,0 +1,168 @@
+package ntfs
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/wabzsy/compression/lznt1"
+)
+
+func TestParseDataRuns(t *testing.T) {
+	input := []byte{
+		0x21, 0x10, 0x00, 0x01, // 16个簇 @ LCN 0x100
+		0x01, 0x10, // 16个稀疏簇
+		0x11, 0x08, 0xF0, // 8个簇 @ LCN 0x100-0x10
+		0x00,
+	}
+	runs, err := ParseDataRuns(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []DataRun{
+		{LCN: 0x100, Length: 0x10},
+		{Length: 0x10, Sparse: true},
+		{LCN: 0xF0, Length: 0x08},
+	}
+	if !reflect.DeepEqual(runs, expected) {
+		t.Fatalf("runs = %+v, expected %+v", runs, expected)
+	}
+
+	for _, input := range [][]byte{
+		{0x21, 0x10, 0x00},       // 被截断
+		{0x11, 0x10, 0xF0, 0x00}, // LCN为负
+		{0x10, 0x01, 0x00},       // 长度大小为0
+	} {
+		if _, err = ParseDataRuns(input); err != ErrInvalidRunList {
+			t.Errorf("ParseDataRuns(% x) = %v, expected ErrInvalidRunList", input, err)
+		}
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	const clusterSize = 512
+	const unitSize = clusterSize << DEFAULT_COMPRESSION_UNIT
+
+	// 单元0: 压缩, 单元1: 稀疏, 单元2: 未压缩(只用到一部分)
+	expected := make([]byte, 2*unitSize+5000)
+	copy(expected, bytes.Repeat([]byte("compressed ntfs unit "), unitSize/21+1)[:unitSize])
+	for i := 2 * unitSize; i < len(expected); i++ {
+		expected[i] = byte(i * 7 / 3)
+	}
+
+	compressed, err := lznt1.Compress(expected[:unitSize])
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressedClusters := int64((len(compressed) + clusterSize - 1) / clusterSize)
+
+	volume := make([]byte, 200*clusterSize)
+	copy(volume[10*clusterSize:], compressed)
+	copy(volume[100*clusterSize:], expected[2*unitSize:])
+
+	attribute := &Attribute{
+		Runs: []DataRun{
+			{LCN: 10, Length: compressedClusters},
+			{Length: 16 - compressedClusters + 16, Sparse: true},
+			{LCN: 100, Length: 16},
+		},
+		ClusterSize:   clusterSize,
+		DataSize:      int64(len(expected)),
+		AllocatedSize: 3 * unitSize,
+	}
+
+	output, err := Decompress(bytes.NewReader(volume), attribute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(output, expected) {
+		t.Fatal("decompressed data does not match")
+	}
+
+	reader, err := NewReaderAt(bytes.NewReader(volume), attribute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part := make([]byte, 300)
+	if _, err = reader.ReadAt(part, unitSize-100); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(part, expected[unitSize-100:unitSize+200]) {
+		t.Fatal("ReadAt across units does not match")
+	}
+}
+
+func TestNewReaderAtInvalid(t *testing.T) {
+	volume := bytes.NewReader(nil)
+
+	// 0x08: 8字节的长度, 没有偏移(稀疏)
+	huge, err := ParseDataRuns([]byte{0x08, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x3F, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, test := range map[string]struct {
+		attribute *Attribute
+		err       error
+	}{
+		"huge run": {
+			&Attribute{Runs: huge, ClusterSize: 512, DataSize: 4096},
+			ErrInvalidRunList,
+		},
+		"runs beyond allocated size": {
+			&Attribute{Runs: []DataRun{{LCN: 1, Length: 17}}, ClusterSize: 512, DataSize: 4096, AllocatedSize: 8192},
+			ErrInvalidRunList,
+		},
+		"lcn overflow": {
+			&Attribute{Runs: []DataRun{{LCN: 1 << 60, Length: 16}}, ClusterSize: 512, DataSize: 8192},
+			ErrInvalidRunList,
+		},
+		"data size beyond allocated size": {
+			&Attribute{ClusterSize: 512, DataSize: 8193, AllocatedSize: 8192},
+			ErrInvalidDataSize,
+		},
+		"compression unit": {
+			&Attribute{ClusterSize: 512, CompressionUnit: 60},
+			ErrInvalidUnit,
+		},
+		"cluster size": {
+			&Attribute{ClusterSize: 500},
+			ErrInvalidCluster,
+		},
+	} {
+		if _, err = NewReaderAt(volume, test.attribute); err != test.err {
+			t.Errorf("%s: err = %v, expected %v", name, err, test.err)
+		}
+	}
+}
+
+func TestDecompressUnit(t *testing.T) {
+	// 大小位为512但有1000字节的未压缩块, 之后是结束标志和簇中剩余的数据
+	first := bytes.Repeat([]byte{'n'}, 1000)
+	second := []byte("second chunk")
+	raw := []byte{0xE7, 0x03}
+	raw = append(raw, first...)
+	raw = append(raw, 0x0B, 0x30)
+	raw = append(raw, second...)
+	raw = append(raw, 0, 0, 0xFF, 0xFF)
+
+	expected, err := lznt1.Decompress(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expected) != lznt1.CHUNK_SIZE+len(second) {
+		t.Fatalf("lznt1 output = %d bytes", len(expected))
+	}
+
+	output, err := DecompressUnit(raw, 0x4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output) != 0x4000 || !bytes.Equal(output[:len(expected)], expected) || !bytes.Equal(output[len(expected):], make([]byte, 0x4000-len(expected))) {
+		t.Fatal("unit does not match lznt1.Decompress")
+	}
+
+	if _, err = DecompressUnit(raw, 0x200); err != lznt1.ErrInvalidData {
+		t.Fatalf("small unit: err = %v, expected lznt1.ErrInvalidData", err)
+	}
+}
@@ -0,0 +1,255 @@
+package quicklz
+
+import (
+	"encoding/binary"
+)
+
+type level1Entry struct {
+	cache  uint32
+	offset int
+}
+
+type level3Entry struct {
+	offset [POINTERS_LEVEL3]int
+}
+
+type Compressor struct {
+	level int
+
+	__input  []byte
+	__output []byte
+
+	hash1   [HASH_VALUES]level1Entry
+	hash3   [HASH_VALUES]level3Entry
+	counter [HASH_VALUES]uint8
+}
+
+func (c *Compressor) putUint(v uint32, n int) {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	c.__output = append(c.__output, buf[:n]...)
+}
+
+func (c *Compressor) setCword(cursor int, v uint32) {
+	binary.LittleEndian.PutUint32(c.__output[cursor:], v)
+}
+
+// compressCore 返回false表示压缩率过低, 应该以未压缩的形式存储
+func (c *Compressor) compressCore(base int) bool {
+	input := c.__input
+	lastByte := len(input) - 1
+	lastMatchStart := lastByte - UNCONDITIONAL_MATCHLEN - UNCOMPRESSED_END
+	src := 0
+	cwordCursor := len(c.__output)
+	c.putUint(0, CWORD_LEN)
+	cword := uint32(1 << 31)
+	lits := 0
+
+	for src <= lastMatchStart {
+		if cword&1 == 1 {
+			// store uncompressed if compression ratio is too low
+			if written := len(c.__output) - base; src > len(input)>>1 && written > src-(src>>5) {
+				return false
+			}
+
+			c.setCword(cwordCursor, cword>>1|1<<31)
+			cwordCursor = len(c.__output)
+			c.putUint(0, CWORD_LEN)
+			cword = 1 << 31
+		}
+
+		fetch := read24(input, src)
+		hash := hashFunc(fetch)
+
+		if c.level == 1 {
+			cached := fetch ^ c.hash1[hash].cache
+			c.hash1[hash].cache = fetch
+			o := c.hash1[hash].offset
+			c.hash1[hash].offset = src
+
+			// offset为0表示哈希表中没有记录(与C版的OFFSET_BASE判断一致)
+			if cached == 0 && o != 0 && (src-o > MINOFFSET || (src == o+1 && lits >= 3 && src > 3 && same(input[src-3:src+3]))) {
+				cword = cword>>1 | 1<<31
+				if input[o+3] != input[src+3] {
+					c.putUint(hash<<4|(3-2), 2)
+					src += 3
+				} else {
+					oldSrc := src
+					src += 4
+					if input[o+src-oldSrc] == input[src] {
+						src++
+						if input[o+src-oldSrc] == input[src] {
+							remaining := lastByte - UNCOMPRESSED_END - (src - 5) + 1
+							if remaining > 255 {
+								remaining = 255
+							}
+							src++
+							for input[o+src-oldSrc] == input[src] && src-oldSrc < remaining {
+								src++
+							}
+						}
+					}
+
+					if length := src - oldSrc; length < 18 {
+						c.putUint(uint32(length-2)|hash<<4, 2)
+					} else {
+						c.putUint(uint32(length)<<16|hash<<4, 3)
+					}
+				}
+				lits = 0
+			} else {
+				lits++
+				c.__output = append(c.__output, input[src])
+				src++
+				cword >>= 1
+			}
+			continue
+		}
+
+		// level 3
+		remaining := lastByte - UNCOMPRESSED_END - src + 1
+		if remaining > 255 {
+			remaining = 255
+		}
+
+		counter := int(c.counter[hash])
+		entry := &c.hash3[hash]
+		o := entry.offset[0]
+		length := 0
+		if o < src-MINOFFSET && counter > 0 && read24(input, o) == fetch {
+			length = 3
+			if input[o+length] == input[src+length] {
+				length = 4
+				for input[o+length] == input[src+length] && length < remaining {
+					length++
+				}
+			}
+		}
+		for k := 1; k < POINTERS_LEVEL3 && counter > k; k++ {
+			candidate := entry.offset[k]
+			if read24(input, candidate) == fetch && candidate < src-MINOFFSET {
+				m := 3
+				for input[candidate+m] == input[src+m] && m < remaining {
+					m++
+				}
+				if m > length || (m == length && candidate > o) {
+					o = candidate
+					length = m
+				}
+			}
+		}
+		entry.offset[counter&(POINTERS_LEVEL3-1)] = src
+		c.counter[hash]++
+
+		if offset := src - o; length > 2 && offset < 131071 {
+			for u := 1; u < length; u++ {
+				h := hashFunc(read24(input, src+u))
+				n := c.counter[h]
+				c.counter[h]++
+				c.hash3[h].offset[int(n)&(POINTERS_LEVEL3-1)] = src + u
+			}
+
+			cword = cword>>1 | 1<<31
+			src += length
+
+			if length == 3 && offset <= 63 {
+				c.__output = append(c.__output, byte(offset<<2))
+			} else if length == 3 && offset <= 16383 {
+				c.putUint(uint32(offset<<2|1), 2)
+			} else if length <= 18 && offset <= 1023 {
+				c.putUint(uint32((length-3)<<2|offset<<6|2), 2)
+			} else if length <= 33 {
+				c.putUint(uint32((length-2)<<2|offset<<7|3), 3)
+			} else {
+				c.putUint(uint32((length-3)<<7|offset<<15|3), 4)
+			}
+		} else {
+			c.__output = append(c.__output, input[src])
+			src++
+			cword >>= 1
+		}
+	}
+
+	for src <= lastByte {
+		if cword&1 == 1 {
+			c.setCword(cwordCursor, cword>>1|1<<31)
+			cwordCursor = len(c.__output)
+			c.putUint(0, CWORD_LEN)
+			cword = 1 << 31
+		}
+		if c.level == 1 && src <= lastByte-3 {
+			fetch := read24(input, src)
+			hash := hashFunc(fetch)
+			c.hash1[hash].offset = src
+			c.hash1[hash].cache = fetch
+		}
+		c.__output = append(c.__output, input[src])
+		src++
+		cword >>= 1
+	}
+
+	for cword&1 != 1 {
+		cword >>= 1
+	}
+	c.setCword(cwordCursor, cword>>1|1<<31)
+
+	// min. size must be 9 bytes so that the qlz_size functions can take 9 bytes as argument
+	for len(c.__output)-base < 9 {
+		c.__output = append(c.__output, 0)
+	}
+
+	return true
+}
+
+func same(bs []byte) bool {
+	for _, b := range bs[1:] {
+		if b != bs[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Compressor) Compress() ([]byte, error) {
+	if c.level != 1 && c.level != 3 {
+		return nil, ErrUnsupportedLevel
+	}
+
+	if len(c.__input) == 0 {
+		return nil, nil
+	}
+
+	header := &Header{
+		HeaderSize:       9,
+		DecompressedSize: uint32(len(c.__input)),
+	}
+	if len(c.__input) < 216 {
+		header.HeaderSize = 3
+	}
+
+	c.__output = make([]byte, header.HeaderSize, len(c.__input)+len(c.__input)/8+int(header.HeaderSize)+16)
+
+	if c.compressCore(int(header.HeaderSize)) {
+		header.Flags |= 1
+	} else {
+		c.__output = append(c.__output[:header.HeaderSize], c.__input...)
+	}
+
+	if header.HeaderSize == 9 {
+		header.Flags |= 2
+	}
+	header.Flags |= uint8(c.level << 2)
+	header.Flags |= 1 << 6
+	header.CompressedSize = uint32(len(c.__output))
+
+	copy(c.__output, header.Bytes())
+
+	return c.__output, nil
+}
+
+func NewCompressor(input []byte, level int) *Compressor {
+	return &Compressor{
+		level:   level,
+		__input: input,
+	}
+}
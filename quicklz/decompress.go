@@ -0,0 +1,192 @@
+package quicklz
+
+import (
+	"encoding/binary"
+)
+
+type Decompressor struct {
+	header *Header
+
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+
+	// level 1 解压时需要和压缩端同步维护的哈希表
+	hash       [HASH_VALUES]int
+	lastHashed int
+}
+
+func (d *Decompressor) fetch(n int) uint32 {
+	// 末尾不足4字节时补0, 与C版直接越界读取的行为等价(多读的部分不会被使用)
+	var buf [4]byte
+	copy(buf[:n], d.__input[d.__inputCursor:])
+	return binary.LittleEndian.Uint32(buf[:])
+}
+
+func (d *Decompressor) updateHash(position int) {
+	d.hash[hashFunc(read24(d.__output, position))] = position
+}
+
+func (d *Decompressor) updateHashUpto(max int) {
+	for d.lastHashed < max {
+		d.lastHashed++
+		d.updateHash(d.lastHashed)
+	}
+}
+
+func (d *Decompressor) need(n int) error {
+	if d.__inputCursor+n > len(d.__input) {
+		return ErrInvalidData
+	}
+	return nil
+}
+
+func (d *Decompressor) decompressCore() error {
+	size := int(d.header.DecompressedSize)
+	level := d.header.Level()
+	lastMatchStart := size - 1 - UNCONDITIONAL_MATCHLEN - UNCOMPRESSED_END
+	cword := uint32(1)
+	d.lastHashed = -1
+
+	for {
+		if cword == 1 {
+			if err := d.need(CWORD_LEN); err != nil {
+				return err
+			}
+			cword = binary.LittleEndian.Uint32(d.__input[d.__inputCursor:]) | 1<<31
+			d.__inputCursor += CWORD_LEN
+		}
+
+		fetch := d.fetch(4)
+
+		if cword&1 == 1 {
+			var offset, length int
+			cword >>= 1
+
+			if level == 1 {
+				hash := (fetch >> 4) & 0xFFF
+				if fetch&0xF != 0 {
+					length = int(fetch&0xF) + 2
+					d.__inputCursor += 2
+				} else {
+					length = int((fetch >> 16) & 0xFF)
+					d.__inputCursor += 3
+				}
+				offset = len(d.__output) - d.hash[hash]
+			} else {
+				switch {
+				case fetch&3 == 0:
+					offset = int((fetch & 0xFF) >> 2)
+					length = 3
+					d.__inputCursor++
+				case fetch&2 == 0:
+					offset = int((fetch & 0xFFFF) >> 2)
+					length = 3
+					d.__inputCursor += 2
+				case fetch&1 == 0:
+					offset = int((fetch & 0xFFFF) >> 6)
+					length = int((fetch>>2)&15) + 3
+					d.__inputCursor += 2
+				case fetch&127 != 3:
+					offset = int((fetch >> 7) & 0x1FFFF)
+					length = int((fetch>>2)&0x1F) + 2
+					d.__inputCursor += 3
+				default:
+					offset = int(fetch >> 15)
+					length = int((fetch>>7)&255) + 3
+					d.__inputCursor += 4
+				}
+			}
+
+			if d.__inputCursor > len(d.__input) || offset <= 0 || offset > len(d.__output) || len(d.__output)+length > size {
+				return ErrInvalidData
+			}
+
+			position := len(d.__output) - offset
+			for i := 0; i < length; i++ {
+				d.__output = append(d.__output, d.__output[position+i])
+			}
+
+			if level == 1 {
+				d.updateHashUpto(len(d.__output) - length)
+				d.lastHashed = len(d.__output) - 1
+			}
+		} else if len(d.__output) < lastMatchStart {
+			// 连续的字面量, 最多4个
+			n := 4
+			for i := 0; i < 4; i++ {
+				if cword>>i&1 != 0 {
+					n = i
+					break
+				}
+			}
+			if err := d.need(n); err != nil {
+				return err
+			}
+			d.__output = append(d.__output, d.__input[d.__inputCursor:d.__inputCursor+n]...)
+			d.__inputCursor += n
+			cword >>= n
+
+			if level == 1 {
+				d.updateHashUpto(len(d.__output) - 3)
+			}
+		} else {
+			// 结尾部分全部是字面量
+			for len(d.__output) < size {
+				if cword == 1 {
+					d.__inputCursor += CWORD_LEN
+					cword = 1 << 31
+				}
+				if err := d.need(1); err != nil {
+					return err
+				}
+				d.__output = append(d.__output, d.__input[d.__inputCursor])
+				d.__inputCursor++
+				cword >>= 1
+			}
+			return nil
+		}
+	}
+}
+
+func (d *Decompressor) Decompress() ([]byte, error) {
+	header, err := ParseHeader(d.__input)
+	if err != nil {
+		return nil, err
+	}
+	d.header = header
+
+	if int(header.CompressedSize) > len(d.__input) {
+		return nil, ErrInvalidData
+	}
+	d.__input = d.__input[:header.CompressedSize]
+	d.__inputCursor = int(header.HeaderSize)
+
+	if header.StreamingBuffer() != 0 {
+		return nil, ErrStreamingBuffer
+	}
+
+	if !header.IsCompressed() {
+		if int(header.CompressedSize-header.HeaderSize) < int(header.DecompressedSize) {
+			return nil, ErrInvalidData
+		}
+		return d.__input[header.HeaderSize : header.HeaderSize+header.DecompressedSize], nil
+	}
+
+	if header.Level() != 1 && header.Level() != 3 {
+		return nil, ErrUnsupportedLevel
+	}
+
+	d.__output = make([]byte, 0, len(d.__input)*2)
+	if err = d.decompressCore(); err != nil {
+		return nil, err
+	}
+
+	return d.__output, nil
+}
+
+func NewDecompressor(input []byte) *Decompressor {
+	return &Decompressor{
+		__input: input,
+	}
+}
@@ -0,0 +1,98 @@
+package quicklz
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	CWORD_LEN              = 4
+	MINOFFSET              = 2
+	UNCONDITIONAL_MATCHLEN = 6
+	UNCOMPRESSED_END       = 4
+	HASH_VALUES            = 4096
+	POINTERS_LEVEL3        = 16
+)
+
+var (
+	ErrInvalidData      = fmt.Errorf("the input data is invalid")
+	ErrInvalidHeader    = fmt.Errorf("the QuickLZ header is invalid")
+	ErrUnsupportedLevel = fmt.Errorf("only QuickLZ level 1 and 3 are supported")
+	ErrStreamingBuffer  = fmt.Errorf("only QuickLZ streaming buffer 0 is supported")
+)
+
+// Header QuickLZ 1.5.x 数据头, 长度为3字节(短头)或9字节(长头)
+type Header struct {
+	Flags            uint8
+	HeaderSize       uint32
+	CompressedSize   uint32 // 包含头部在内的总长度
+	DecompressedSize uint32
+}
+
+func (h *Header) IsCompressed() bool {
+	return h.Flags&1 != 0
+}
+
+func (h *Header) Level() int {
+	return int(h.Flags>>2) & 3
+}
+
+func (h *Header) StreamingBuffer() int {
+	return int(h.Flags>>4) & 3
+}
+
+func ParseHeader(input []byte) (*Header, error) {
+	if len(input) < 3 {
+		return nil, ErrInvalidHeader
+	}
+
+	header := &Header{
+		Flags: input[0],
+	}
+
+	if header.Flags&2 != 0 {
+		if len(input) < 9 {
+			return nil, ErrInvalidHeader
+		}
+		header.HeaderSize = 9
+		header.CompressedSize = binary.LittleEndian.Uint32(input[1:])
+		header.DecompressedSize = binary.LittleEndian.Uint32(input[5:])
+	} else {
+		header.HeaderSize = 3
+		header.CompressedSize = uint32(input[1])
+		header.DecompressedSize = uint32(input[2])
+	}
+
+	if header.CompressedSize < header.HeaderSize {
+		return nil, ErrInvalidHeader
+	}
+
+	return header, nil
+}
+
+func (h *Header) Bytes() []byte {
+	if h.HeaderSize == 3 {
+		return []byte{h.Flags, byte(h.CompressedSize), byte(h.DecompressedSize)}
+	}
+	buf := make([]byte, 9)
+	buf[0] = h.Flags
+	binary.LittleEndian.PutUint32(buf[1:], h.CompressedSize)
+	binary.LittleEndian.PutUint32(buf[5:], h.DecompressedSize)
+	return buf
+}
+
+func hashFunc(i uint32) uint32 {
+	return ((i >> 12) ^ i) & (HASH_VALUES - 1)
+}
+
+func read24(buf []byte, position int) uint32 {
+	return uint32(buf[position]) | uint32(buf[position+1])<<8 | uint32(buf[position+2])<<16
+}
+
+func Compress(input []byte, level int) ([]byte, error) {
+	return NewCompressor(input, level).Compress()
+}
+
+func Decompress(input []byte) ([]byte, error) {
+	return NewDecompressor(input).Decompress()
+}
@@ -0,0 +1,252 @@
+package wim
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"io"
+	"time"
+	"unicode/utf16"
+)
+
+// 元数据资源: 安全描述符表(总长度按8字节对齐) + 目录项树
+// 目录项(WIM_DENTRY_DISK)固定部分为0x66字节, 之后是UTF-16LE的文件名和短文件名,
+// 然后是 num_extra_streams 个额外数据流项; 每个目录的子项从 subdir_offset 开始连续存放, 以长度为0的项结束
+
+const (
+	DENTRY_SIZE       = 0x66
+	STREAM_ENTRY_SIZE = 0x26
+
+	FILE_ATTRIBUTE_DIRECTORY     = 0x10
+	FILE_ATTRIBUTE_REPARSE_POINT = 0x400
+)
+
+type Stream struct {
+	// Name 为空时是未命名数据流(文件内容)
+	Name string
+	Hash [sha1.Size]byte
+}
+
+type File struct {
+	Name           string
+	ShortName      string
+	Path           string
+	Attributes     uint32
+	SecurityID     int32
+	CreationTime   time.Time
+	LastAccessTime time.Time
+	LastWriteTime  time.Time
+	// Hash 未命名数据流的SHA-1, 全0表示空文件
+	Hash    [sha1.Size]byte
+	Streams []Stream
+}
+
+func (f *File) IsDir() bool {
+	return f.Attributes&FILE_ATTRIBUTE_DIRECTORY != 0
+}
+
+// filetime 100纳秒, 从1601-01-01开始
+func filetime(v uint64) time.Time {
+	if v == 0 {
+		return time.Time{}
+	}
+	const epochDelta = 116444736000000000
+	v100 := int64(v) - epochDelta
+	return time.Unix(v100/10000000, (v100%10000000)*100).UTC()
+}
+
+func decodeName(input []byte) string {
+	units := make([]uint16, len(input)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(input[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+func align8(v uint64) uint64 {
+	return (v + 7) &^ 7
+}
+
+type metadataParser struct {
+	data    []byte
+	files   []*File
+	visited map[uint64]bool
+}
+
+// parseEntry 解析offset处的目录项, 返回下一个兄弟项的位置; 目录结束时返回0
+func (p *metadataParser) parseEntry(offset uint64, parent string) (uint64, error) {
+	data := p.data
+	if offset+8 > uint64(len(data)) {
+		return 0, ErrInvalidMetadata
+	}
+	length := binary.LittleEndian.Uint64(data[offset:])
+	if length <= 8 {
+		return 0, nil
+	}
+	if length < DENTRY_SIZE || offset+length > uint64(len(data)) {
+		return 0, ErrInvalidMetadata
+	}
+	entry := data[offset : offset+length]
+
+	file := &File{
+		Attributes:     binary.LittleEndian.Uint32(entry[0x08:]),
+		SecurityID:     int32(binary.LittleEndian.Uint32(entry[0x0C:])),
+		CreationTime:   filetime(binary.LittleEndian.Uint64(entry[0x28:])),
+		LastAccessTime: filetime(binary.LittleEndian.Uint64(entry[0x30:])),
+		LastWriteTime:  filetime(binary.LittleEndian.Uint64(entry[0x38:])),
+	}
+	subdir := binary.LittleEndian.Uint64(entry[0x10:])
+	copy(file.Hash[:], entry[0x40:])
+	streams := int(binary.LittleEndian.Uint16(entry[0x60:]))
+	shortNameLen := uint64(binary.LittleEndian.Uint16(entry[0x62:]))
+	nameLen := uint64(binary.LittleEndian.Uint16(entry[0x64:]))
+
+	if DENTRY_SIZE+nameLen+shortNameLen > length {
+		return 0, ErrInvalidMetadata
+	}
+	file.Name = decodeName(entry[DENTRY_SIZE : DENTRY_SIZE+nameLen])
+	// 文件名之后有2字节的结束符
+	position := uint64(DENTRY_SIZE) + nameLen
+	if nameLen > 0 {
+		position += 2
+	}
+	if shortNameLen > 0 && position+shortNameLen <= length {
+		file.ShortName = decodeName(entry[position : position+shortNameLen])
+	}
+
+	next := offset + align8(length)
+	for i := 0; i < streams; i++ {
+		if next+STREAM_ENTRY_SIZE > uint64(len(data)) {
+			return 0, ErrInvalidMetadata
+		}
+		streamLength := binary.LittleEndian.Uint64(data[next:])
+		if streamLength < STREAM_ENTRY_SIZE || next+streamLength > uint64(len(data)) {
+			return 0, ErrInvalidMetadata
+		}
+		var stream Stream
+		copy(stream.Hash[:], data[next+0x10:])
+		streamNameLen := uint64(binary.LittleEndian.Uint16(data[next+0x24:]))
+		if STREAM_ENTRY_SIZE+streamNameLen > streamLength {
+			return 0, ErrInvalidMetadata
+		}
+		stream.Name = decodeName(data[next+STREAM_ENTRY_SIZE : next+STREAM_ENTRY_SIZE+streamNameLen])
+		file.Streams = append(file.Streams, stream)
+		next += align8(streamLength)
+	}
+
+	// 有命名数据流时, 未命名数据流保存在名字为空的额外数据流项中
+	if file.Hash == ([sha1.Size]byte{}) {
+		for _, stream := range file.Streams {
+			if stream.Name == "" {
+				file.Hash = stream.Hash
+				break
+			}
+		}
+	}
+
+	if parent == "" && file.Name == "" {
+		// 根目录
+		file.Path = "/"
+	} else if parent == "/" {
+		file.Path = "/" + file.Name
+	} else {
+		file.Path = parent + "/" + file.Name
+	}
+	p.files = append(p.files, file)
+
+	if file.IsDir() && subdir != 0 {
+		if p.visited[subdir] {
+			return 0, ErrInvalidMetadata
+		}
+		p.visited[subdir] = true
+		if err := p.parseDirectory(subdir, file.Path); err != nil {
+			return 0, err
+		}
+	}
+
+	return next, nil
+}
+
+func (p *metadataParser) parseDirectory(offset uint64, parent string) error {
+	for offset != 0 {
+		next, err := p.parseEntry(offset, parent)
+		if err != nil {
+			return err
+		}
+		offset = next
+	}
+	return nil
+}
+
+// ParseMetadata 解析解压后的元数据资源, 按目录树的先序返回所有文件, 第一个为根目录
+func ParseMetadata(data []byte) ([]*File, error) {
+	if len(data) < 8 {
+		return nil, ErrInvalidMetadata
+	}
+	root := align8(uint64(binary.LittleEndian.Uint32(data)))
+	if root < 8 {
+		root = 8
+	}
+
+	p := &metadataParser{
+		data:    data,
+		visited: map[uint64]bool{root: true},
+	}
+	if _, err := p.parseEntry(root, ""); err != nil {
+		return nil, err
+	}
+	return p.files, nil
+}
+
+// Files 列出第image个镜像(从1开始)中的所有文件
+func (w *WIM) Files(image int) ([]*File, error) {
+	if image < 1 || image > len(w.metadata) {
+		return nil, ErrInvalidImage
+	}
+	data, err := w.ReadResource(w.metadata[image-1].Resource)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMetadata(data)
+}
+
+// OpenStream 返回hash对应数据流内容的io.Reader, 全0的hash为空数据流
+func (w *WIM) OpenStream(hash [sha1.Size]byte) (io.Reader, error) {
+	if hash == ([sha1.Size]byte{}) {
+		return bytes.NewReader(nil), nil
+	}
+	entry, err := w.Find(hash)
+	if err != nil {
+		return nil, err
+	}
+	return w.OpenResource(entry.Resource)
+}
+
+// OpenFile 返回文件未命名数据流内容的io.Reader
+func (w *WIM) OpenFile(file *File) (io.Reader, error) {
+	return w.OpenStream(file.Hash)
+}
+
+// ReadFile 读取文件内容并校验SHA-1
+func (w *WIM) ReadFile(file *File) ([]byte, error) {
+	reader, err := w.OpenFile(file)
+	if err != nil {
+		return nil, err
+	}
+	output, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if file.Hash != ([sha1.Size]byte{}) && sha1.Sum(output) != file.Hash {
+		return nil, ErrHashMismatch
+	}
+	return output, nil
+}
+
+// Size 返回文件未命名数据流的原始大小
+func (w *WIM) Size(file *File) int64 {
+	if entry, err := w.Find(file.Hash); err == nil {
+		return entry.Resource.OriginalSize
+	}
+	return 0
+}
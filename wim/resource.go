@@ -0,0 +1,161 @@
+package wim
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/wabzsy/compression/lzx"
+	"github.com/wabzsy/compression/wof"
+	"github.com/wabzsy/compression/xpresshuff"
+)
+
+// 压缩资源的开头为块偏移表, 格式与WofCompressedData相同(wof.ChunkTable)
+
+type resourceReader struct {
+	reader      io.ReaderAt
+	resource    *ResourceHeader
+	compression Compression
+	chunkSize   int64
+
+	table   *wof.ChunkTable
+	chunk   int
+	pending []byte
+	remain  int64
+}
+
+// OpenResource 返回资源解压后内容的io.Reader, 按块读取并解压
+func (w *WIM) OpenResource(resource *ResourceHeader) (io.Reader, error) {
+	if resource.OriginalSize < 0 || resource.Size < 0 || resource.Offset < 0 ||
+		resource.Size > w.size || resource.Offset > w.size-resource.Size {
+		return nil, ErrInvalidResource
+	}
+
+	if !resource.IsCompressed() {
+		if resource.Size < resource.OriginalSize {
+			return nil, ErrInvalidResource
+		}
+		return io.NewSectionReader(w.reader, resource.Offset, resource.OriginalSize), nil
+	}
+
+	// solid资源(ESD)使用另外的块表格式, 且通常为LZMS压缩
+	if resource.Flags&RESHDR_FLAG_SOLID != 0 {
+		return nil, ErrNotSupported
+	}
+
+	compression := w.Header.Compression()
+	switch compression {
+	case CompressionXPRESS, CompressionLZX:
+	default:
+		return nil, ErrNotSupported
+	}
+
+	chunkSize := int64(w.Header.ChunkSize)
+	if chunkSize&(chunkSize-1) != 0 || chunkSize > MAX_CHUNK_SIZE {
+		return nil, ErrInvalidHeader
+	}
+
+	r := &resourceReader{
+		reader:      w.reader,
+		resource:    resource,
+		compression: compression,
+		chunkSize:   chunkSize,
+		remain:      resource.OriginalSize,
+	}
+	if err := r.readTable(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ReadResource 读取并解压整个资源
+func (w *WIM) ReadResource(resource *ResourceHeader) ([]byte, error) {
+	reader, err := w.OpenResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	// 压缩资源的原始大小不可信, 预分配不超过文件大小, 之后按实际解压出的数据增长
+	capacity := resource.OriginalSize
+	if capacity > w.size {
+		capacity = w.size
+	}
+	output := bytes.NewBuffer(make([]byte, 0, capacity))
+	if _, err = io.Copy(output, reader); err != nil {
+		return nil, err
+	}
+	if int64(output.Len()) != resource.OriginalSize {
+		return nil, ErrInvalidResource
+	}
+	return output.Bytes(), nil
+}
+
+func (r *resourceReader) readTable() error {
+	_, tableSize := wof.ChunkTableSize(r.resource.OriginalSize, r.chunkSize)
+	if tableSize > r.resource.Size {
+		return ErrInvalidResource
+	}
+
+	table := make([]byte, tableSize)
+	if _, err := r.reader.ReadAt(table, r.resource.Offset); err != nil {
+		return err
+	}
+
+	t, err := wof.ParseChunkTable(table, r.resource.OriginalSize, r.resource.Size, r.chunkSize)
+	if err != nil {
+		return ErrInvalidResource
+	}
+	r.table = t
+	return nil
+}
+
+func (r *resourceReader) decompressChunk(index int) ([]byte, error) {
+	start, end, size := r.table.Chunk(index)
+	compressed := make([]byte, end-start)
+	if _, err := r.reader.ReadAt(compressed, r.resource.Offset+start); err != nil {
+		return nil, err
+	}
+	if r.table.IsStored(index) {
+		return compressed, nil
+	}
+
+	var output []byte
+	var err error
+	if r.compression == CompressionLZX {
+		// 窗口大小由块大小决定, 最后一个不完整的块也使用相同的窗口
+		windowBits := lzx.MIN_WINDOW_BITS
+		for int64(1)<<windowBits < r.chunkSize {
+			windowBits++
+		}
+		if windowBits > lzx.MAX_WINDOW_BITS {
+			return nil, ErrNotSupported
+		}
+		output, err = lzx.NewWIMDecompressor(compressed, windowBits).Decompress(int(size))
+	} else {
+		output, err = xpresshuff.Decompress(compressed, int(size))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(output)) != size {
+		return nil, ErrInvalidResource
+	}
+	return output, nil
+}
+
+func (r *resourceReader) Read(p []byte) (int, error) {
+	if r.remain == 0 {
+		return 0, io.EOF
+	}
+	if len(r.pending) == 0 {
+		data, err := r.decompressChunk(r.chunk)
+		if err != nil {
+			return 0, err
+		}
+		r.chunk++
+		r.pending = data
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	r.remain -= int64(n)
+	return n, nil
+}
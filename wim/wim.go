@@ -0,0 +1,254 @@
+package wim
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	HEADER_SIZE       = 208
+	RESHDR_SIZE       = 24
+	LOOKUP_ENTRY_SIZE = 50
+
+	DEFAULT_CHUNK_SIZE = 0x8000
+	// MAX_CHUNK_SIZE XPRESS/LZX压缩允许的最大块大小(LZX的最大窗口)
+	MAX_CHUNK_SIZE = 1 << 21
+)
+
+// 头部标志(WIM_HDR_FLAG_*)
+const (
+	FLAG_COMPRESSION     = 0x00000002
+	FLAG_READONLY        = 0x00000004
+	FLAG_SPANNED         = 0x00000008
+	FLAG_RESOURCE_ONLY   = 0x00000010
+	FLAG_METADATA_ONLY   = 0x00000020
+	FLAG_COMPRESS_XPRESS = 0x00020000
+	FLAG_COMPRESS_LZX    = 0x00040000
+	FLAG_COMPRESS_LZMS   = 0x00080000
+)
+
+// 资源标志(WIM_RESHDR_FLAG_*)
+const (
+	RESHDR_FLAG_FREE       = 0x01
+	RESHDR_FLAG_METADATA   = 0x02
+	RESHDR_FLAG_COMPRESSED = 0x04
+	RESHDR_FLAG_SPANNED    = 0x08
+	RESHDR_FLAG_SOLID      = 0x10
+)
+
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionXPRESS
+	CompressionLZX
+	CompressionLZMS
+)
+
+var (
+	Magic        = []byte("MSWIM\x00\x00\x00")
+	MagicWIMBoot = []byte("WLPWM\x00\x00\x00")
+)
+
+var (
+	ErrInvalidHeader   = fmt.Errorf("the WIM header is invalid")
+	ErrInvalidResource = fmt.Errorf("the WIM resource is invalid")
+	ErrInvalidMetadata = fmt.Errorf("the WIM metadata is invalid")
+	ErrInvalidImage    = fmt.Errorf("the WIM image index is invalid")
+	ErrNotFound        = fmt.Errorf("the stream is not found in the lookup table")
+	ErrHashMismatch    = fmt.Errorf("the SHA-1 hash of the stream does not match")
+	ErrNotSupported    = fmt.Errorf("the resource compression is not supported")
+	ErrUnknownSize     = fmt.Errorf("the size of the reader is unknown, use OpenWithSize")
+)
+
+// ResourceHeader RESHDR_DISK_SHORT: 7字节压缩后大小 + 1字节标志 + 偏移 + 原始大小
+type ResourceHeader struct {
+	Size         int64
+	Flags        uint8
+	Offset       int64
+	OriginalSize int64
+}
+
+func (h *ResourceHeader) IsCompressed() bool {
+	return h.Flags&RESHDR_FLAG_COMPRESSED != 0
+}
+
+func ParseResourceHeader(input []byte) *ResourceHeader {
+	v := binary.LittleEndian.Uint64(input)
+	return &ResourceHeader{
+		Size:         int64(v & 0x00FFFFFFFFFFFFFF),
+		Flags:        uint8(v >> 56),
+		Offset:       int64(binary.LittleEndian.Uint64(input[8:])),
+		OriginalSize: int64(binary.LittleEndian.Uint64(input[16:])),
+	}
+}
+
+type Header struct {
+	Magic          [8]byte
+	Size           uint32
+	Version        uint32
+	Flags          uint32
+	ChunkSize      uint32
+	GUID           [16]byte
+	PartNumber     uint16
+	TotalParts     uint16
+	ImageCount     uint32
+	LookupTable    *ResourceHeader
+	XMLData        *ResourceHeader
+	BootMetadata   *ResourceHeader
+	BootIndex      uint32
+	IntegrityTable *ResourceHeader
+}
+
+func ParseHeader(input []byte) (*Header, error) {
+	if len(input) < HEADER_SIZE || (!bytes.Equal(input[:8], Magic) && !bytes.Equal(input[:8], MagicWIMBoot)) {
+		return nil, ErrInvalidHeader
+	}
+
+	header := &Header{
+		Size:           binary.LittleEndian.Uint32(input[0x08:]),
+		Version:        binary.LittleEndian.Uint32(input[0x0C:]),
+		Flags:          binary.LittleEndian.Uint32(input[0x10:]),
+		ChunkSize:      binary.LittleEndian.Uint32(input[0x14:]),
+		PartNumber:     binary.LittleEndian.Uint16(input[0x28:]),
+		TotalParts:     binary.LittleEndian.Uint16(input[0x2A:]),
+		ImageCount:     binary.LittleEndian.Uint32(input[0x2C:]),
+		LookupTable:    ParseResourceHeader(input[0x30:]),
+		XMLData:        ParseResourceHeader(input[0x48:]),
+		BootMetadata:   ParseResourceHeader(input[0x60:]),
+		BootIndex:      binary.LittleEndian.Uint32(input[0x78:]),
+		IntegrityTable: ParseResourceHeader(input[0x7C:]),
+	}
+	copy(header.Magic[:], input)
+	copy(header.GUID[:], input[0x18:])
+
+	if header.Size < HEADER_SIZE {
+		return nil, ErrInvalidHeader
+	}
+	if header.ChunkSize == 0 {
+		header.ChunkSize = DEFAULT_CHUNK_SIZE
+	}
+	return header, nil
+}
+
+func (h *Header) Compression() Compression {
+	if h.Flags&FLAG_COMPRESSION == 0 {
+		return CompressionNone
+	}
+	switch {
+	case h.Flags&FLAG_COMPRESS_XPRESS != 0:
+		return CompressionXPRESS
+	case h.Flags&FLAG_COMPRESS_LZX != 0:
+		return CompressionLZX
+	case h.Flags&FLAG_COMPRESS_LZMS != 0:
+		return CompressionLZMS
+	}
+	return CompressionNone
+}
+
+// LookupEntry 查找表中的一项, 通过SHA-1找到对应的资源
+type LookupEntry struct {
+	Resource       *ResourceHeader
+	PartNumber     uint16
+	ReferenceCount uint32
+	Hash           [sha1.Size]byte
+}
+
+type WIM struct {
+	Header *Header
+	Lookup []*LookupEntry
+
+	reader   io.ReaderAt
+	size     int64
+	byHash   map[[sha1.Size]byte]*LookupEntry
+	metadata []*LookupEntry
+}
+
+// readerSize 通过Size()(bytes.Reader, io.SectionReader等)或Stat()(os.File)获取reader的大小
+func readerSize(reader io.ReaderAt) (int64, error) {
+	switch r := reader.(type) {
+	case interface{ Size() int64 }:
+		return r.Size(), nil
+	case interface{ Stat() (os.FileInfo, error) }:
+		info, err := r.Stat()
+		if err != nil {
+			return 0, err
+		}
+		return info.Size(), nil
+	}
+	return 0, ErrUnknownSize
+}
+
+// Open 打开WIM文件, reader需要能获取大小, 否则使用OpenWithSize
+func Open(reader io.ReaderAt) (*WIM, error) {
+	size, err := readerSize(reader)
+	if err != nil {
+		return nil, err
+	}
+	return OpenWithSize(reader, size)
+}
+
+// OpenWithSize 打开大小为size的WIM文件, 所有资源的位置和大小都按size校验
+func OpenWithSize(reader io.ReaderAt, size int64) (*WIM, error) {
+	if size < HEADER_SIZE {
+		return nil, ErrInvalidHeader
+	}
+	buf := make([]byte, HEADER_SIZE)
+	if _, err := reader.ReadAt(buf, 0); err != nil {
+		return nil, err
+	}
+	header, err := ParseHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WIM{
+		Header: header,
+		reader: reader,
+		size:   size,
+		byHash: make(map[[sha1.Size]byte]*LookupEntry),
+	}
+
+	table, err := w.ReadResource(header.LookupTable)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i+LOOKUP_ENTRY_SIZE <= len(table); i += LOOKUP_ENTRY_SIZE {
+		entry := &LookupEntry{
+			Resource:       ParseResourceHeader(table[i:]),
+			PartNumber:     binary.LittleEndian.Uint16(table[i+24:]),
+			ReferenceCount: binary.LittleEndian.Uint32(table[i+26:]),
+		}
+		copy(entry.Hash[:], table[i+30:])
+		w.Lookup = append(w.Lookup, entry)
+
+		if entry.Resource.Flags&RESHDR_FLAG_METADATA != 0 {
+			w.metadata = append(w.metadata, entry)
+		} else {
+			w.byHash[entry.Hash] = entry
+		}
+	}
+
+	return w, nil
+}
+
+// XML 返回XML数据(UTF-16LE)
+func (w *WIM) XML() ([]byte, error) {
+	return w.ReadResource(w.Header.XMLData)
+}
+
+func (w *WIM) ImageCount() int {
+	return len(w.metadata)
+}
+
+// Find 通过SHA-1查找数据流
+func (w *WIM) Find(hash [sha1.Size]byte) (*LookupEntry, error) {
+	if entry, ok := w.byHash[hash]; ok {
+		return entry, nil
+	}
+	return nil, ErrNotFound
+}
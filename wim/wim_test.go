@@ -0,0 +1,255 @@
+package wim
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"io"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/wabzsy/compression/xpresshuff"
+)
+
+const testChunkSize = 0x1000
+
+func putResourceHeader(output []byte, resource ResourceHeader) {
+	binary.LittleEndian.PutUint64(output, uint64(resource.Size)|uint64(resource.Flags)<<56)
+	binary.LittleEndian.PutUint64(output[8:], uint64(resource.Offset))
+	binary.LittleEndian.PutUint64(output[16:], uint64(resource.OriginalSize))
+}
+
+func encodeName(name string) []byte {
+	units := utf16.Encode([]rune(name))
+	output := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(output[2*i:], u)
+	}
+	return output
+}
+
+// dentry 构造一个目录项(已按8字节对齐)
+func dentry(name string, attributes uint32, subdir uint64, hash [sha1.Size]byte) []byte {
+	encoded := encodeName(name)
+	length := DENTRY_SIZE + len(encoded)
+	if len(encoded) > 0 {
+		length += 2
+	}
+	entry := make([]byte, align8(uint64(length)))
+	binary.LittleEndian.PutUint64(entry, uint64(length))
+	binary.LittleEndian.PutUint32(entry[0x08:], attributes)
+	binary.LittleEndian.PutUint32(entry[0x0C:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint64(entry[0x10:], subdir)
+	copy(entry[0x40:], hash[:])
+	binary.LittleEndian.PutUint16(entry[0x64:], uint16(len(encoded)))
+	copy(entry[DENTRY_SIZE:], encoded)
+	return entry
+}
+
+// compressResource 按块压缩为XPRESS资源: 偏移表 + 各个块
+func compressResource(t *testing.T, data []byte) []byte {
+	var chunks [][]byte
+	for start := 0; start < len(data); start += testChunkSize {
+		end := start + testChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		compressed, err := xpresshuff.Compress(data[start:end])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(compressed) >= end-start {
+			compressed = data[start:end]
+		}
+		chunks = append(chunks, compressed)
+	}
+	table := make([]byte, 4*(len(chunks)-1))
+	offset := 0
+	for i, chunk := range chunks[:len(chunks)-1] {
+		offset += len(chunk)
+		binary.LittleEndian.PutUint32(table[4*i:], uint32(offset))
+	}
+	return append(table, bytes.Join(chunks, nil)...)
+}
+
+type testWIM struct {
+	file     []byte
+	content  []byte
+	contents ResourceHeader
+}
+
+// buildWIM 一个XPRESS压缩的WIM: 文件内容(压缩), 元数据(未压缩), 查找表(未压缩)
+// 镜像中只有根目录和/hello.txt
+func buildWIM(t *testing.T) *testWIM {
+	w := &testWIM{
+		content: bytes.Repeat([]byte("hello from a hand-built WIM\n"), 300),
+	}
+	hash := sha1.Sum(w.content)
+
+	file := make([]byte, HEADER_SIZE)
+
+	compressed := compressResource(t, w.content)
+	w.contents = ResourceHeader{
+		Size:         int64(len(compressed)),
+		Flags:        RESHDR_FLAG_COMPRESSED,
+		Offset:       int64(len(file)),
+		OriginalSize: int64(len(w.content)),
+	}
+	file = append(file, compressed...)
+	for len(file)%8 != 0 {
+		file = append(file, 0)
+	}
+
+	// 安全描述符表(8字节) + 根目录 + 结束项 + 根目录的子项 + 结束项
+	metadata := make([]byte, 8)
+	binary.LittleEndian.PutUint32(metadata, 8)
+	root := dentry("", FILE_ATTRIBUTE_DIRECTORY, 0, [sha1.Size]byte{})
+	subdir := uint64(len(metadata) + len(root) + 8)
+	binary.LittleEndian.PutUint64(root[0x10:], subdir)
+	metadata = append(metadata, root...)
+	metadata = append(metadata, make([]byte, 8)...)
+	metadata = append(metadata, dentry("hello.txt", 0x20, 0, hash)...)
+	metadata = append(metadata, make([]byte, 8)...)
+	metadataResource := ResourceHeader{
+		Size:         int64(len(metadata)),
+		Flags:        RESHDR_FLAG_METADATA,
+		Offset:       int64(len(file)),
+		OriginalSize: int64(len(metadata)),
+	}
+	file = append(file, metadata...)
+
+	lookup := make([]byte, 2*LOOKUP_ENTRY_SIZE)
+	putResourceHeader(lookup, w.contents)
+	binary.LittleEndian.PutUint16(lookup[24:], 1)
+	binary.LittleEndian.PutUint32(lookup[26:], 1)
+	copy(lookup[30:], hash[:])
+	putResourceHeader(lookup[LOOKUP_ENTRY_SIZE:], metadataResource)
+	binary.LittleEndian.PutUint16(lookup[LOOKUP_ENTRY_SIZE+24:], 1)
+	binary.LittleEndian.PutUint32(lookup[LOOKUP_ENTRY_SIZE+26:], 1)
+	lookupResource := ResourceHeader{
+		Size:         int64(len(lookup)),
+		Offset:       int64(len(file)),
+		OriginalSize: int64(len(lookup)),
+	}
+	file = append(file, lookup...)
+
+	copy(file, Magic)
+	binary.LittleEndian.PutUint32(file[0x08:], HEADER_SIZE)
+	binary.LittleEndian.PutUint32(file[0x0C:], 0x10D00)
+	binary.LittleEndian.PutUint32(file[0x10:], FLAG_COMPRESSION|FLAG_COMPRESS_XPRESS)
+	binary.LittleEndian.PutUint32(file[0x14:], testChunkSize)
+	binary.LittleEndian.PutUint16(file[0x28:], 1)
+	binary.LittleEndian.PutUint16(file[0x2A:], 1)
+	binary.LittleEndian.PutUint32(file[0x2C:], 1)
+	putResourceHeader(file[0x30:], lookupResource)
+
+	w.file = file
+	return w
+}
+
+func TestOpen(t *testing.T) {
+	test := buildWIM(t)
+	w, err := Open(bytes.NewReader(test.file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Header.Compression() != CompressionXPRESS || w.ImageCount() != 1 || len(w.Lookup) != 2 {
+		t.Fatalf("compression = %d, images = %d, lookup = %d", w.Header.Compression(), w.ImageCount(), len(w.Lookup))
+	}
+
+	files, err := w.Files(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Path != "/" || !files[0].IsDir() || files[1].Path != "/hello.txt" {
+		t.Fatalf("files = %+v", files)
+	}
+
+	content, err := w.ReadFile(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, test.content) {
+		t.Fatal("file content does not match")
+	}
+	if w.Size(files[1]) != int64(len(test.content)) {
+		t.Fatalf("size = %d, expected %d", w.Size(files[1]), len(test.content))
+	}
+}
+
+func TestReadResourceInvalid(t *testing.T) {
+	test := buildWIM(t)
+	w, err := Open(bytes.NewReader(test.file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(test.file))
+
+	for name, resource := range map[string]ResourceHeader{
+		"beyond file": {Size: 100, Offset: size - 50, OriginalSize: 100},
+		"huge size":   {Size: 1 << 55, Flags: RESHDR_FLAG_COMPRESSED, Offset: HEADER_SIZE, OriginalSize: 1 << 40},
+		"huge original size": {
+			Size:         test.contents.Size,
+			Flags:        RESHDR_FLAG_COMPRESSED,
+			Offset:       test.contents.Offset,
+			OriginalSize: 1<<63 - 1,
+		},
+		"negative offset": {Size: 10, Offset: -1, OriginalSize: 10},
+	} {
+		if _, err = w.ReadResource(&resource); err != ErrInvalidResource {
+			t.Errorf("%s: err = %v, expected ErrInvalidResource", name, err)
+		}
+	}
+
+	// 不可压缩的数据按原样存储, 把第一个块的结尾向后移动1字节, 使它超过块大小
+	random := make([]byte, 3*testChunkSize)
+	seed := uint32(1)
+	for i := range random {
+		seed = seed*1103515245 + 12345
+		random[i] = byte(seed >> 16)
+	}
+	stored := compressResource(t, random)
+	binary.LittleEndian.PutUint32(stored, testChunkSize+1)
+	broken := append(append([]byte(nil), test.file...), stored...)
+	resource := &ResourceHeader{
+		Size:         int64(len(stored)),
+		Flags:        RESHDR_FLAG_COMPRESSED,
+		Offset:       size,
+		OriginalSize: int64(len(random)),
+	}
+	w, err = Open(bytes.NewReader(broken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.ReadResource(resource); err != ErrInvalidResource {
+		t.Errorf("chunk larger than the chunk size: err = %v, expected ErrInvalidResource", err)
+	}
+
+	// 头部的块大小不是2的幂
+	broken = append([]byte(nil), test.file...)
+	binary.LittleEndian.PutUint32(broken[0x14:], testChunkSize+1)
+	w, err = Open(bytes.NewReader(broken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.ReadResource(&test.contents); err != ErrInvalidHeader {
+		t.Errorf("chunk size: err = %v, expected ErrInvalidHeader", err)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	test := buildWIM(t)
+
+	// 查找表超出文件结尾
+	if _, err := OpenWithSize(bytes.NewReader(test.file), int64(len(test.file)-1)); err != ErrInvalidResource {
+		t.Errorf("truncated: err = %v, expected ErrInvalidResource", err)
+	}
+	if _, err := Open(bytes.NewReader(test.file[:HEADER_SIZE-1])); err != ErrInvalidHeader {
+		t.Errorf("short header: err = %v, expected ErrInvalidHeader", err)
+	}
+
+	// 没有Size()和Stat()的reader
+	if _, err := Open(struct{ io.ReaderAt }{bytes.NewReader(test.file)}); err != ErrUnknownSize {
+		t.Errorf("unknown size: err = %v, expected ErrUnknownSize", err)
+	}
+}
@@ -0,0 +1,178 @@
+package wof
+
+import (
+	"io"
+	"sync"
+
+	"github.com/wabzsy/compression/lzx"
+	"github.com/wabzsy/compression/xpresshuff"
+)
+
+// ReaderAt WofCompressedData的随机读取, 流的开头为块偏移表(ChunkTable)
+type ReaderAt struct {
+	// Workers 一次读取跨越多个块时的并行数量
+	Workers int
+
+	stream    []byte
+	size      int64
+	algorithm Algorithm
+	chunkSize int64
+	table     *ChunkTable
+
+	mutex       sync.Mutex
+	cachedChunk int64
+	cached      []byte
+}
+
+func NewReaderAt(stream []byte, size int64, algorithm Algorithm) (*ReaderAt, error) {
+	chunkSize := int64(algorithm.ChunkSize())
+	if chunkSize == 0 {
+		return nil, ErrInvalidAlgorithm
+	}
+	if size < 0 {
+		return nil, ErrInvalidData
+	}
+
+	table, err := ParseChunkTable(stream, size, int64(len(stream)), chunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReaderAt{
+		Workers:     1,
+		stream:      stream,
+		size:        size,
+		algorithm:   algorithm,
+		chunkSize:   chunkSize,
+		table:       table,
+		cachedChunk: -1,
+	}, nil
+}
+
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+func (r *ReaderAt) Chunks() int {
+	return r.table.Chunks()
+}
+
+// DecompressChunk 解压第index个块
+func (r *ReaderAt) DecompressChunk(index int) ([]byte, error) {
+	if index < 0 || index >= r.Chunks() {
+		return nil, ErrInvalidData
+	}
+
+	start, end, size := r.table.Chunk(index)
+	compressed := r.stream[start:end]
+
+	if r.table.IsStored(index) {
+		return compressed, nil
+	}
+
+	var output []byte
+	var err error
+	if r.algorithm == LZX {
+		output, err = lzx.DecompressWIM(compressed, int(size))
+	} else {
+		output, err = xpresshuff.Decompress(compressed, int(size))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(output)) != size {
+		return nil, ErrInvalidData
+	}
+	return output, nil
+}
+
+func (r *ReaderAt) chunk(index int64) ([]byte, error) {
+	r.mutex.Lock()
+	if r.cachedChunk == index {
+		data := r.cached
+		r.mutex.Unlock()
+		return data, nil
+	}
+	r.mutex.Unlock()
+
+	data, err := r.DecompressChunk(int(index))
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	r.cachedChunk, r.cached = index, data
+	r.mutex.Unlock()
+	return data, nil
+}
+
+func (r *ReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, ErrInvalidData
+	}
+	if off >= r.size {
+		if len(p) == 0 {
+			return 0, nil
+		}
+		return 0, io.EOF
+	}
+
+	end := off + int64(len(p))
+	if end > r.size {
+		end = r.size
+	}
+	first := off / r.chunkSize
+	last := (end - 1) / r.chunkSize
+
+	// 每个块写入p中不重叠的区域, 可以并行
+	copyChunk := func(index int64) error {
+		data, err := r.chunk(index)
+		if err != nil {
+			return err
+		}
+		start := index * r.chunkSize
+		from, to := int64(0), int64(len(data))
+		if start < off {
+			from = off - start
+		}
+		if start+to > end {
+			to = end - start
+		}
+		copy(p[start+from-off:], data[from:to])
+		return nil
+	}
+
+	if r.Workers <= 1 || first == last {
+		for index := first; index <= last; index++ {
+			if err := copyChunk(index); err != nil {
+				return 0, err
+			}
+		}
+	} else {
+		var wg sync.WaitGroup
+		var once sync.Once
+		var firstErr error
+		semaphore := make(chan struct{}, r.Workers)
+		for index := first; index <= last; index++ {
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func(index int64) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				if err := copyChunk(index); err != nil {
+					once.Do(func() { firstErr = err })
+				}
+			}(index)
+		}
+		wg.Wait()
+		if firstErr != nil {
+			return 0, firstErr
+		}
+	}
+
+	n := int(end - off)
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
@@ -0,0 +1,89 @@
+package wof
+
+import "encoding/binary"
+
+// ChunkTable 块偏移表, WofCompressedData和WIM的压缩资源使用相同的格式:
+// 开头为 chunks-1 个块偏移(原始大小超过4GB时为8字节, 否则为4字节), 偏移相对于偏移表的结尾,
+// 第一个块的偏移(0)不存储; 压缩后大小等于原始块大小的块未压缩
+type ChunkTable struct {
+	// Size 原始大小
+	Size      int64
+	ChunkSize int64
+	// Offsets 每个块的压缩数据相对于偏移表开头的位置, 最后一项为压缩数据的结尾
+	Offsets []int64
+}
+
+// ChunkTableSize 原始大小为size时块的数量和偏移表的大小
+func ChunkTableSize(size, chunkSize int64) (chunks, tableSize int64) {
+	chunks = size / chunkSize
+	if size%chunkSize != 0 {
+		chunks++
+	}
+	entrySize := int64(4)
+	if size > 0xFFFFFFFF {
+		entrySize = 8
+	}
+	if chunks > 0 {
+		tableSize = (chunks - 1) * entrySize
+	}
+	return chunks, tableSize
+}
+
+// ParseChunkTable 解析偏移表, compressedSize为包括偏移表在内的压缩数据的大小, table至少有ChunkTableSize给出的长度.
+// 每个块至少有1字节的压缩数据, 且不超过原始块的大小
+func ParseChunkTable(table []byte, size, compressedSize, chunkSize int64) (*ChunkTable, error) {
+	if size < 0 || compressedSize < 0 || chunkSize <= 0 {
+		return nil, ErrInvalidTable
+	}
+
+	chunks, tableSize := ChunkTableSize(size, chunkSize)
+	if tableSize > compressedSize || chunks > compressedSize-tableSize || tableSize > int64(len(table)) {
+		return nil, ErrInvalidTable
+	}
+
+	t := &ChunkTable{
+		Size:      size,
+		ChunkSize: chunkSize,
+		Offsets:   make([]int64, chunks+1),
+	}
+	for i := int64(1); i < chunks; i++ {
+		if size > 0xFFFFFFFF {
+			t.Offsets[i] = int64(binary.LittleEndian.Uint64(table[(i-1)*8:]))
+		} else {
+			t.Offsets[i] = int64(binary.LittleEndian.Uint32(table[(i-1)*4:]))
+		}
+	}
+	t.Offsets[chunks] = compressedSize - tableSize
+
+	for i := 0; i < int(chunks); i++ {
+		if length := t.Offsets[i+1] - t.Offsets[i]; length <= 0 || length > t.chunkSize(i) {
+			return nil, ErrInvalidTable
+		}
+	}
+	for i := range t.Offsets {
+		t.Offsets[i] += tableSize
+	}
+	return t, nil
+}
+
+func (t *ChunkTable) Chunks() int {
+	return len(t.Offsets) - 1
+}
+
+func (t *ChunkTable) chunkSize(index int) int64 {
+	if remain := t.Size - int64(index)*t.ChunkSize; remain < t.ChunkSize {
+		return remain
+	}
+	return t.ChunkSize
+}
+
+// Chunk 第index个块的压缩数据的范围 [start, end) 和原始大小
+func (t *ChunkTable) Chunk(index int) (start, end, size int64) {
+	return t.Offsets[index], t.Offsets[index+1], t.chunkSize(index)
+}
+
+// IsStored 第index个块是否未压缩
+func (t *ChunkTable) IsStored(index int) bool {
+	start, end, size := t.Chunk(index)
+	return end-start == size
+}
@@ -0,0 +1,62 @@
+package wof
+
+import "fmt"
+
+// Algorithm FILE_PROVIDER_COMPRESSION_* (compact /EXE:...)
+type Algorithm uint32
+
+const (
+	XPRESS4K  Algorithm = 0
+	LZX       Algorithm = 1
+	XPRESS8K  Algorithm = 2
+	XPRESS16K Algorithm = 3
+)
+
+var (
+	ErrInvalidData      = fmt.Errorf("the input data is invalid")
+	ErrInvalidAlgorithm = fmt.Errorf("the WOF compression algorithm is invalid")
+	ErrInvalidTable     = fmt.Errorf("the WOF chunk table is invalid")
+)
+
+func (a Algorithm) ChunkSize() int {
+	switch a {
+	case XPRESS4K:
+		return 0x1000
+	case XPRESS8K:
+		return 0x2000
+	case XPRESS16K:
+		return 0x4000
+	case LZX:
+		return 0x8000
+	}
+	return 0
+}
+
+func (a Algorithm) String() string {
+	switch a {
+	case XPRESS4K:
+		return "XPRESS4K"
+	case XPRESS8K:
+		return "XPRESS8K"
+	case XPRESS16K:
+		return "XPRESS16K"
+	case LZX:
+		return "LZX"
+	}
+	return fmt.Sprintf("Algorithm(%d)", uint32(a))
+}
+
+// Decompress 解压整个WofCompressedData流, workers大于1时按块并行解压
+func Decompress(stream []byte, size int64, algorithm Algorithm, workers int) ([]byte, error) {
+	reader, err := NewReaderAt(stream, size, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	reader.Workers = workers
+
+	output := make([]byte, size)
+	if _, err = reader.ReadAt(output, 0); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
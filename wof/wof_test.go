@@ -0,0 +1,152 @@
+package wof
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/wabzsy/compression/xpresshuff"
+)
+
+// buildStream 构造XPRESS4K的WofCompressedData: 第一个和第三个块压缩, 第二个块不可压缩(原样存储)
+func buildStream(t *testing.T) ([]byte, []byte) {
+	chunkSize := XPRESS4K.ChunkSize()
+	expected := make([]byte, 2*chunkSize+1000)
+	copy(expected, bytes.Repeat([]byte("wof chunk "), chunkSize/10+1)[:chunkSize])
+	seed := uint32(1)
+	for i := chunkSize; i < 2*chunkSize; i++ {
+		seed = seed*1103515245 + 12345
+		expected[i] = byte(seed >> 16)
+	}
+	copy(expected[2*chunkSize:], bytes.Repeat([]byte{'z'}, 1000))
+
+	var chunks [][]byte
+	for start := 0; start < len(expected); start += chunkSize {
+		end := start + chunkSize
+		if end > len(expected) {
+			end = len(expected)
+		}
+		if start == chunkSize {
+			chunks = append(chunks, expected[start:end])
+			continue
+		}
+		compressed, err := xpresshuff.Compress(expected[start:end])
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, compressed)
+	}
+
+	// 偏移表不包含第一个块
+	table := make([]byte, 4*(len(chunks)-1))
+	offset := 0
+	for i, chunk := range chunks[:len(chunks)-1] {
+		offset += len(chunk)
+		binary.LittleEndian.PutUint32(table[4*i:], uint32(offset))
+	}
+	return append(table, bytes.Join(chunks, nil)...), expected
+}
+
+func TestReaderAt(t *testing.T) {
+	stream, expected := buildStream(t)
+
+	for _, workers := range []int{1, 4} {
+		reader, err := NewReaderAt(stream, int64(len(expected)), XPRESS4K)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader.Workers = workers
+		if reader.Chunks() != 3 {
+			t.Fatalf("chunks = %d, expected 3", reader.Chunks())
+		}
+
+		output := make([]byte, len(expected))
+		if n, err := reader.ReadAt(output, 0); err != nil || n != len(expected) {
+			t.Fatalf("workers=%d: n = %d, err = %v", workers, n, err)
+		}
+		if !bytes.Equal(output, expected) {
+			t.Fatalf("workers=%d: data does not match", workers)
+		}
+
+		// 跨越块边界并超出结尾的读取
+		part := make([]byte, 200)
+		n, err := reader.ReadAt(part, int64(len(expected)-150))
+		if n != 150 || err == nil {
+			t.Fatalf("workers=%d: n = %d, err = %v, expected 150 and EOF", workers, n, err)
+		}
+		if !bytes.Equal(part[:n], expected[len(expected)-150:]) {
+			t.Fatalf("workers=%d: partial data does not match", workers)
+		}
+	}
+}
+
+func TestNewReaderAtInvalid(t *testing.T) {
+	stream, expected := buildStream(t)
+	size := int64(len(expected))
+
+	// 偏移递减
+	broken := append([]byte(nil), stream...)
+	binary.LittleEndian.PutUint32(broken[4:], 1)
+	if _, err := NewReaderAt(broken, size, XPRESS4K); err != ErrInvalidTable {
+		t.Errorf("decreasing offsets: err = %v, expected ErrInvalidTable", err)
+	}
+
+	// 偏移表比数据还大
+	if _, err := NewReaderAt(stream[:4], size, XPRESS4K); err != ErrInvalidTable {
+		t.Errorf("truncated table: err = %v, expected ErrInvalidTable", err)
+	}
+	if _, err := NewReaderAt(stream, 1<<50, XPRESS4K); err != ErrInvalidTable {
+		t.Errorf("huge size: err = %v, expected ErrInvalidTable", err)
+	}
+
+	if _, err := NewReaderAt(stream, size, Algorithm(7)); err != ErrInvalidAlgorithm {
+		t.Errorf("algorithm: err = %v, expected ErrInvalidAlgorithm", err)
+	}
+}
+
+func TestChunkTable(t *testing.T) {
+	// 三个块: 压缩, 原样存储, 最后一个不完整的块原样存储
+	table := make([]byte, 8)
+	binary.LittleEndian.PutUint32(table, 10)
+	binary.LittleEndian.PutUint32(table[4:], 10+0x1000)
+	c, err := ParseChunkTable(table, 0x2000+5, 8+10+0x1000+5, 0x1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Chunks() != 3 || c.IsStored(0) || !c.IsStored(1) || !c.IsStored(2) {
+		t.Fatalf("chunks = %d, offsets = %v", c.Chunks(), c.Offsets)
+	}
+	if start, end, size := c.Chunk(2); start != 8+10+0x1000 || end != start+5 || size != 5 {
+		t.Fatalf("chunk 2 = [%d, %d), size %d", start, end, size)
+	}
+
+	// 原始大小超过4GB时使用8字节的偏移, 每个块1字节
+	size := int64(0x100000000) + 1
+	chunks, tableSize := ChunkTableSize(size, 0x8000)
+	if chunks != 0x20001 || tableSize != 8*0x20000 {
+		t.Fatalf("chunks = %#x, table size = %#x", chunks, tableSize)
+	}
+	table = make([]byte, tableSize)
+	for i := int64(1); i < chunks; i++ {
+		binary.LittleEndian.PutUint64(table[8*(i-1):], uint64(i))
+	}
+	if c, err = ParseChunkTable(table, size, tableSize+chunks, 0x8000); err != nil {
+		t.Fatal(err)
+	}
+	if start, end, _ := c.Chunk(int(chunks - 1)); start != tableSize+chunks-1 || end != tableSize+chunks {
+		t.Fatalf("last chunk = [%d, %d)", start, end)
+	}
+
+	for name, offsets := range map[string][]uint32{
+		"larger than the chunk size": {10, 10 + 0x1001},
+		"empty chunk":                {10, 10},
+		"decreasing offsets":         {10, 5},
+	} {
+		table := make([]byte, 8)
+		binary.LittleEndian.PutUint32(table, offsets[0])
+		binary.LittleEndian.PutUint32(table[4:], offsets[1])
+		if _, err = ParseChunkTable(table, 0x2000+5, 8+10+0x1000+5, 0x1000); err != ErrInvalidTable {
+			t.Errorf("%s: err = %v, expected ErrInvalidTable", name, err)
+		}
+	}
+}
@@ -15,17 +15,23 @@ const (
 type Level struct {
 	MaxChain   int
 	NiceLength int
+	// LazyDepth 使用当前位置的匹配前向后检查的位置数, 0表示贪心
+	LazyDepth int
+	// Optimal 按token的实际大小计算代价的最优解析, 此时LazyDepth无效
+	Optimal bool
 }
 
 var (
-	Level1 = Level{NiceLength: 16, MaxChain: 4}
-	Level2 = Level{NiceLength: 32, MaxChain: 8}
-	Level3 = Level{NiceLength: 48, MaxChain: 11}
-	Level4 = Level{NiceLength: 64, MaxChain: 16}
-	Level5 = Level{NiceLength: 128, MaxChain: 32}
-	Level6 = Level{NiceLength: 256, MaxChain: 64}
-	Level7 = Level{NiceLength: 512, MaxChain: 128}
-	Level8 = Level{NiceLength: 1<<32 - 1, MaxChain: 1<<32 - 1}
+	Level1  = Level{NiceLength: 16, MaxChain: 4}
+	Level2  = Level{NiceLength: 32, MaxChain: 8}
+	Level3  = Level{NiceLength: 48, MaxChain: 11}
+	Level4  = Level{NiceLength: 64, MaxChain: 16}
+	Level5  = Level{NiceLength: 128, MaxChain: 32}
+	Level6  = Level{NiceLength: 256, MaxChain: 64}
+	Level7  = Level{NiceLength: 512, MaxChain: 128}
+	Level8  = Level{NiceLength: 1<<32 - 1, MaxChain: 1<<32 - 1}
+	Level9  = Level{NiceLength: 1<<32 - 1, MaxChain: 1<<32 - 1, LazyDepth: 1}
+	Level10 = Level{NiceLength: 1<<32 - 1, MaxChain: 512, Optimal: true}
 )
 
 type Dictionary struct {
@@ -89,7 +95,8 @@ func (d *Dictionary) Find(input []byte, cursor int) (length, offset int) {
 
 	//prefix := binary.LittleEndian.Uint16(input[cursor : cursor+2])
 
-	for chainLength != 0 && ok && position >= cursor-MAX_OFFSET {
+	// 提前Fill(向后查看)时窗口中最早的位置可能已被覆盖, 遇到cursor之后的位置时停止
+	for chainLength != 0 && ok && position >= cursor-MAX_OFFSET && position < cursor {
 		if bytes.Equal(input[position:position+2], input[cursor:cursor+2]) {
 			//i := 2
 			i := 3
@@ -139,6 +146,10 @@ type Compressor struct {
 
 	__flagCount  int
 	__flagCursor int
+
+	__flags    uint32
+	__halfByte *uint8
+	__filledTo int
 }
 
 func (c *Compressor) MaxCompressedSize() int {
@@ -185,6 +196,87 @@ func (c *Compressor) __SetByte(v uint8) {
 	c.__outputCursor++
 }
 
+// __match 写入长度为length(>=3)的匹配, 长度的第一个半字节与前一个未用完的半字节共用一个字节
+func (c *Compressor) __match(length, offset int) {
+	c.__inputCursor += length
+	length -= 3
+	c.__SetUint16(uint16(((offset - 1) << 3) | Min(length, 7)))
+
+	if length >= 0x7 {
+		length -= 0x7
+		if c.__halfByte != nil {
+			*c.__halfByte |= byte(Min(length, 0xF) << 4)
+			c.__halfByte = nil
+		} else {
+			c.__halfByte = &c.__output[c.__outputCursor]
+			*c.__halfByte = byte(Min(length, 0xF))
+			c.__outputCursor++
+		}
+		if length >= 0xF {
+			length -= 0xF
+			c.__SetByte(byte(Min(length, 0xFF)))
+			if length >= 0xFF {
+				length += 0xF + 0x7
+				if length <= 0xFFFF {
+					c.__SetUint16(uint16(length))
+				} else {
+					c.__SetUint16(uint16(0))
+					c.__SetUint32(uint32(length))
+				}
+			}
+		}
+	}
+}
+
+// __token 写入一个literal(length < 3)或匹配, 并更新标志
+func (c *Compressor) __token(length, offset int) {
+	c.__flags <<= 1
+	if length < 3 {
+		c.__literal()
+	} else {
+		c.__match(length, offset)
+		c.__flags |= 1
+	}
+	c.__SetFlags(c.__flags)
+}
+
+// find 查找cursor处的最长匹配, 需要时先Fill
+func (c *Compressor) find(cursor int) (length, offset int) {
+	if cursor >= len(c.__input)-2 {
+		return 0, 0
+	}
+	for c.__filledTo <= cursor {
+		c.__filledTo = c.dict.Fill(c.__input, c.__filledTo)
+	}
+	return c.dict.Find(c.__input, cursor)
+}
+
+func (c *Compressor) greedy() {
+	for c.__inputCursor < len(c.__input)-2 {
+		if c.__filledTo <= c.__inputCursor {
+			c.__filledTo = c.dict.Fill(c.__input, c.__filledTo)
+		}
+		c.__token(c.dict.Find(c.__input, c.__inputCursor))
+	}
+}
+
+// lazy 后面LazyDepth个位置中有节省更多的匹配时, 当前位置只输出literal
+func (c *Compressor) lazy() {
+	for c.__inputCursor < len(c.__input)-2 {
+		length, offset := c.find(c.__inputCursor)
+		if length >= 3 && length < c.dict.level.NiceLength {
+			saved := savedBits(length, c.__halfByte != nil)
+			for k := 1; k <= c.dict.level.LazyDepth; k++ {
+				if next, _ := c.find(c.__inputCursor + k); next >= 3 && savedBits(next, c.__halfByte != nil) > saved {
+					length = 0
+					break
+				}
+			}
+		}
+		c.__token(length, offset)
+	}
+}
+
 func (c *Compressor) Compress() ([]byte, error) {
 	if len(c.__input) == 0 {
 		return nil, nil
@@ -197,65 +289,20 @@ func (c *Compressor) Compress() ([]byte, error) {
 	// copy the first byte
 	c.__literal()
 
-	var halfByte *uint8
-	flags := uint32(0)
-	filledTo := 0
-
-	for c.__inputCursor < len(c.__input)-2 {
-
-		//if c.__outputCursor >= 0x21e892 {
-		//	log.Println("debugger break point")
-		//}
-
-		if filledTo <= c.__inputCursor {
-			filledTo = c.dict.Fill(c.__input, filledTo)
-		}
-		flags <<= 1
-		length, offset := c.dict.Find(c.__input, c.__inputCursor)
-
-		if length < 3 {
-			c.__literal()
-		} else {
-			c.__inputCursor += length
-			length -= 3
-			c.__SetUint16(uint16(((offset - 1) << 3) | Min(length, 7)))
-
-			if length >= 0x7 {
-				length -= 0x7
-				if halfByte != nil {
-					*halfByte |= byte(Min(length, 0xF) << 4)
-					halfByte = nil
-				} else {
-					halfByte = &c.__output[c.__outputCursor]
-					*halfByte = byte(Min(length, 0xF))
-					c.__outputCursor++
-				}
-				if length >= 0xF {
-					length -= 0xF
-					c.__SetByte(byte(Min(length, 0xFF)))
-					if length >= 0xFF {
-						length += 0xF + 0x7
-						if length <= 0xFFFF {
-							c.__SetUint16(uint16(length))
-						} else {
-							c.__SetUint16(uint16(0))
-							c.__SetUint32(uint32(length))
-						}
-					}
-				}
-			}
-			flags |= 1
-		}
-		c.__SetFlags(flags)
+	switch {
+	case c.dict.level.Optimal:
+		c.optimal()
+	case c.dict.level.LazyDepth > 0:
+		c.lazy()
+	default:
+		c.greedy()
 	}
 
 	for c.__inputCursor < len(c.__input) {
-		c.__literal()
-		flags <<= 1
-		c.__SetFlags(flags)
+		c.__token(0, 0)
 	}
 
-	c.__SetEndFlags(flags)
+	c.__SetEndFlags(c.__flags)
 
 	return c.__output[:c.__outputCursor], nil
 }
@@ -7,13 +7,21 @@ import (
 )
 
 var (
-	ErrInvalidData = fmt.Errorf("the input data is invalid")
+	ErrInvalidData    = fmt.Errorf("the input data is invalid")
+	ErrTrailingData   = fmt.Errorf("there is trailing data after the compressed data")
+	ErrOutputSize     = fmt.Errorf("the decompressed data is shorter than the output size")
+	ErrOutputOverflow = fmt.Errorf("the decompressed data exceeds the output size")
 )
 
 type Decompressor struct {
 	__input       []byte
 	__inputCursor int
 
+	// outputSize 大于0时, 输出达到该大小后立即结束(忽略之后的填充数据)
+	outputSize int
+	// strict 最后一个匹配超出outputSize时返回ErrOutputOverflow, 否则截断
+	strict bool
+
 	reader *bytes.Reader
 	output *bytes.Buffer
 }
@@ -42,7 +50,7 @@ func (d *Decompressor) literal() error {
 	}
 }
 
-func (d *Decompressor) ReadByte() (uint8, error) {
+func (d *Decompressor) ReadByte() (byte, error) {
 	return d.reader.ReadByte()
 }
 
@@ -55,6 +63,13 @@ func SetBitsAreHighest(x uint32) bool {
 }
 
 func (d *Decompressor) Decompress() ([]byte, error) {
+	output, _, err := d.DecompressN()
+	return output, err
+}
+
+// DecompressN 同时返回使用的输入字节数. XPRESS没有结束标志, 只有设置了outputSize时才能在输入结束前停止,
+// 否则总是使用全部输入
+func (d *Decompressor) DecompressN() ([]byte, int, error) {
 	d.reader = bytes.NewReader(d.__input)
 	d.output = &bytes.Buffer{}
 
@@ -63,7 +78,7 @@ func (d *Decompressor) Decompress() ([]byte, error) {
 	for d.reader.Len()-4 > 0 {
 		flags, err := d.ReadUint32()
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		flagged := flags & 0x80000000
 		flags = (flags << 1) | 1
@@ -73,7 +88,7 @@ func (d *Decompressor) Decompress() ([]byte, error) {
 				// 以下
 				symbol, err := d.ReadUint16()
 				if err != nil {
-					return nil, fmt.Errorf("XPRESS Decompression Error: Invalid data: Unable to read 2 bytes for offset/length")
+					return nil, 0, fmt.Errorf("XPRESS Decompression Error: Invalid data: Unable to read 2 bytes for offset/length")
 				}
 
 				offset := (symbol >> 3) + 1
@@ -85,7 +100,7 @@ func (d *Decompressor) Decompress() ([]byte, error) {
 						halfByte = nil
 					} else {
 						if n, err := d.ReadByte(); err != nil {
-							return nil, fmt.Errorf("XPRESS Decompression Error: Invalid data: Unable to read a half-byte for length")
+							return nil, 0, fmt.Errorf("XPRESS Decompression Error: Invalid data: Unable to read a half-byte for length")
 						} else {
 							halfByte = &n
 							length = uint32(*halfByte & 0xF)
@@ -94,26 +109,26 @@ func (d *Decompressor) Decompress() ([]byte, error) {
 
 					if length == 0xF {
 						if n, err := d.ReadByte(); err != nil {
-							return nil, fmt.Errorf("XPRESS Decompression Error: Invalid data: Unable to read a byte for length")
+							return nil, 0, fmt.Errorf("XPRESS Decompression Error: Invalid data: Unable to read a byte for length")
 						} else {
 							length = uint32(n)
 						}
 
 						if length == 0xFF {
 							if n, err := d.ReadUint16(); err != nil {
-								return nil, fmt.Errorf("XPRESS Decompression Error: Invalid data: Unable to read two bytes for length")
+								return nil, 0, fmt.Errorf("XPRESS Decompression Error: Invalid data: Unable to read two bytes for length")
 							} else {
 								length = uint32(n)
 							}
 
 							if length == 0 {
 								if length, err = d.ReadUint32(); err != nil {
-									return nil, fmt.Errorf("XPRESS Decompression Error: Invalid data: Unable to read four bytes for length")
+									return nil, 0, fmt.Errorf("XPRESS Decompression Error: Invalid data: Unable to read four bytes for length")
 								}
 							}
 
 							if length < 0xF+0x7 {
-								return nil, fmt.Errorf("XPRESS Decompression Error: Invalid data: Invalid length")
+								return nil, 0, fmt.Errorf("XPRESS Decompression Error: Invalid data: Invalid length")
 							}
 							length -= 0xF + 0x7
 						}
@@ -125,7 +140,7 @@ func (d *Decompressor) Decompress() ([]byte, error) {
 
 				// 以上
 				if d.output.Len()-int(offset) < 0 {
-					return nil, ErrInvalidData
+					return nil, 0, ErrInvalidData
 				}
 
 				if offset == 1 {
@@ -142,20 +157,32 @@ func (d *Decompressor) Decompress() ([]byte, error) {
 				}
 			} else {
 				if err = d.literal(); err != nil {
-					return nil, err
+					return nil, 0, err
 				}
 			}
 
+			if d.outputSize > 0 && d.output.Len() >= d.outputSize {
+				if d.strict && d.output.Len() > d.outputSize {
+					return nil, 0, ErrOutputOverflow
+				}
+				consumed := len(d.__input) - d.reader.Len()
+				// 压缩时用完32个标志位后会立即预留下一个标志, 结束时写入0xFFFFFFFF, 它也属于压缩数据
+				if flags<<1 == 0 && d.reader.Len() >= 4 && binary.LittleEndian.Uint32(d.__input[consumed:]) == 0xFFFFFFFF {
+					consumed += 4
+				}
+				return d.output.Bytes()[:d.outputSize], consumed, nil
+			}
+
 			flagged = flags & 0x80000000
 			flags <<= 1
 
 			if d.reader.Len() == 0 {
 				// 检查异常
 				if flagged == 0 || !SetBitsAreHighest(flags) {
-					return nil, ErrInvalidData
+					return nil, 0, ErrInvalidData
 				}
 				// 返回结果
-				return d.output.Bytes(), nil
+				return d.output.Bytes(), len(d.__input), nil
 			}
 
 			if flags == 0 {
@@ -164,7 +191,12 @@ func (d *Decompressor) Decompress() ([]byte, error) {
 		}
 	}
 
-	return nil, ErrInvalidData
+	// 最后一个标志组正好用完时, 压缩数据以一个没有对应数据的0xFFFFFFFF结束
+	if d.reader.Len() == 4 && d.output.Len() > 0 && binary.LittleEndian.Uint32(d.__input[len(d.__input)-4:]) == 0xFFFFFFFF {
+		return d.output.Bytes(), len(d.__input), nil
+	}
+
+	return nil, 0, ErrInvalidData
 }
 
 func NewDecompressor(input []byte) *Decompressor {
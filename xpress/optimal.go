@@ -0,0 +1,160 @@
+package xpress
+
+// Level10的最优解析: 每个literal或匹配在32位标志字中占1bit, literal再占1字节,
+// 匹配占2字节的偏移/长度, 长度不够时再用半字节、字节和更长的扩展长度.
+// 两个匹配的扩展长度共用一个半字节的字节, 所以每个位置按 之前是否留下了另一半 区分两个状态,
+// 输入按OPTIMAL_BLOCK_SIZE分块求最短路径; 找到长度达到NiceLength或超出块结尾的匹配时在该处结束当前块
+
+const (
+	OPTIMAL_BLOCK_SIZE = 0x10000
+	// optimalFullLengths 小于等于该长度时尝试每个长度, 更长的匹配只尝试最大长度
+	optimalFullLengths = 32
+
+	literalBits = 1 + 8
+)
+
+// matchBits 长度为length的匹配的bit数, pending表示之前有未用完的半字节;
+// 返回写入之后是否有未用完的半字节
+func matchBits(length int, pending bool) (int, bool) {
+	bits := 1 + 16
+	length -= 3
+	if length >= 0x7 {
+		// 新开一个字节时它的另一半留给下一个匹配
+		if pending {
+			pending = false
+		} else {
+			bits += 8
+			pending = true
+		}
+		length -= 0x7
+		if length >= 0xF {
+			bits += 8
+			length -= 0xF
+			if length >= 0xFF {
+				if length+0xF+0x7 <= 0xFFFF {
+					bits += 16
+				} else {
+					bits += 16 + 32
+				}
+			}
+		}
+	}
+	return bits, pending
+}
+
+// savedBits 使用长度为length的匹配比逐个literal节省的bit数
+func savedBits(length int, pending bool) int {
+	bits, _ := matchBits(length, pending)
+	return literalBits*length - bits
+}
+
+type optimalNode struct {
+	cost   int
+	length int32
+	offset int32
+	// pending 到达之前是否有未用完的半字节
+	pending bool
+}
+
+type optimalParser struct {
+	c     *Compressor
+	nodes [][2]optimalNode
+}
+
+func (p *optimalParser) relax(index int, pending bool, node optimalNode) {
+	state := 0
+	if pending {
+		state = 1
+	}
+	if current := &p.nodes[index][state]; current.cost < 0 || node.cost < current.cost {
+		*current = node
+	}
+}
+
+// parse 解析[start, end), 返回最优路径上的(长度, 偏移); 因长匹配截断时返回截断的位置
+func (p *optimalParser) parse(start, end int) ([]optimalNode, int) {
+	c := p.c
+	size := end - start + 1
+
+	if cap(p.nodes) < size {
+		p.nodes = make([][2]optimalNode, size)
+	}
+	p.nodes = p.nodes[:size]
+	for i := range p.nodes {
+		p.nodes[i][0].cost, p.nodes[i][1].cost = -1, -1
+	}
+	p.relax(0, c.__halfByte != nil, optimalNode{})
+
+	for i := start; i < end; i++ {
+		index := i - start
+		length, offset := c.find(i)
+		// 超出块结尾的匹配截断后会多出一个token, 和足够长的匹配一样在此处结束当前块
+		if length >= c.dict.level.NiceLength || length > end-i {
+			end = i
+			break
+		}
+
+		for state, pending := range []bool{false, true} {
+			node := &p.nodes[index][state]
+			if node.cost < 0 {
+				continue
+			}
+
+			p.relax(index+1, pending, optimalNode{cost: node.cost + literalBits, pending: pending})
+
+			for l := 3; l <= length; l++ {
+				if l > optimalFullLengths && l != length {
+					l = length
+				}
+				bits, next := matchBits(l, pending)
+				p.relax(index+l, next, optimalNode{cost: node.cost + bits, length: int32(l), offset: int32(offset), pending: pending})
+			}
+		}
+	}
+
+	// 回溯
+	index, state := end-start, 0
+	if last := p.nodes[index]; last[0].cost < 0 || (last[1].cost >= 0 && last[1].cost < last[0].cost) {
+		state = 1
+	}
+	var path []optimalNode
+	for index > 0 {
+		node := p.nodes[index][state]
+		path = append(path, node)
+		if node.length == 0 {
+			index--
+		} else {
+			index -= int(node.length)
+		}
+		state = 0
+		if node.pending {
+			state = 1
+		}
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, end
+}
+
+func (c *Compressor) optimal() {
+	p := &optimalParser{c: c}
+
+	for c.__inputCursor < len(c.__input)-2 {
+		start := c.__inputCursor
+		end := start + OPTIMAL_BLOCK_SIZE
+		if end > len(c.__input) {
+			end = len(c.__input)
+		}
+
+		path, cut := p.parse(start, end)
+		for _, node := range path {
+			c.__token(int(node.length), int(node.offset))
+		}
+
+		// 结束当前块的匹配不参与最优解析, 直接写出
+		if cut < end {
+			c.__token(c.find(cut))
+		}
+	}
+}
@@ -13,3 +13,30 @@ func Compress(input []byte) ([]byte, error) {
 func Decompress(source []byte) ([]byte, error) {
 	return NewDecompressor(source).Decompress()
 }
+
+// DecompressWithSize 已知解压后大小时使用, 输出达到outputSize后忽略剩余的输入
+func DecompressWithSize(source []byte, outputSize int) ([]byte, error) {
+	d := NewDecompressor(source)
+	d.outputSize = outputSize
+	return d.Decompress()
+}
+
+// DecompressN 与DecompressWithSize相同, 同时返回使用的输入字节数; outputSize为0时使用全部输入.
+// strict为true时, 输出达到outputSize后还有剩余的输入返回ErrTrailingData, 输出不足outputSize返回ErrOutputSize,
+// 最后一个匹配超出outputSize返回ErrOutputOverflow
+func DecompressN(source []byte, outputSize int, strict bool) ([]byte, int, error) {
+	d := NewDecompressor(source)
+	d.outputSize = outputSize
+	d.strict = strict
+	output, consumed, err := d.DecompressN()
+	if err != nil {
+		return nil, 0, err
+	}
+	if strict && consumed < len(source) {
+		return nil, 0, ErrTrailingData
+	}
+	if strict && outputSize > 0 && len(output) < outputSize {
+		return nil, 0, ErrOutputSize
+	}
+	return output, consumed, nil
+}
@@ -0,0 +1,111 @@
+package xpress
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecompressN(t *testing.T) {
+	input := []byte("abcdefghijklmnopqrstuvwxyz0123456789")
+	for len(input) < 160 {
+		input = append(input, input[len(input)/3])
+	}
+	compressed, err := Compress(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, consumed, err := DecompressN(compressed, len(input), true)
+	if err != nil || !bytes.Equal(output, input) || consumed != len(compressed) {
+		t.Fatalf("exact size: consumed = %d, err = %v", consumed, err)
+	}
+
+	// 输出不足outputSize
+	if _, _, err = DecompressN(compressed, 500, true); err != ErrOutputSize {
+		t.Errorf("short output: err = %v, expected ErrOutputSize", err)
+	}
+	if output, _, err = DecompressN(compressed, 500, false); err != nil || !bytes.Equal(output, input) {
+		t.Errorf("short output, not strict: err = %v", err)
+	}
+
+	// 之后的数据
+	trailing := append(append([]byte(nil), compressed...), 1, 2, 3, 4, 5)
+	if _, _, err = DecompressN(trailing, len(input), true); err != ErrTrailingData {
+		t.Errorf("trailing data: err = %v, expected ErrTrailingData", err)
+	}
+	if _, consumed, err = DecompressN(trailing, len(input), false); err != nil || consumed != len(compressed) {
+		t.Errorf("trailing data, not strict: consumed = %d, err = %v", consumed, err)
+	}
+
+	// 一个literal加一个长度99的匹配, 匹配超出outputSize
+	repeated := bytes.Repeat([]byte{'a'}, 100)
+	if compressed, err = Compress(repeated); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = DecompressN(compressed, 50, true); err != ErrOutputOverflow {
+		t.Errorf("overflow: err = %v, expected ErrOutputOverflow", err)
+	}
+	if output, _, err = DecompressN(compressed, 50, false); err != nil || !bytes.Equal(output, repeated[:50]) {
+		t.Errorf("overflow, not strict: err = %v", err)
+	}
+}
+
+func TestFullFlagGroup(t *testing.T) {
+	// 不可压缩的数据, 标志组正好用完时以一个单独的0xFFFFFFFF结束
+	for _, size := range []int{31, 32, 33, 64} {
+		input := make([]byte, size)
+		for i := range input {
+			input[i] = byte(i * 7)
+		}
+		compressed, err := Compress(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output, err := Decompress(compressed)
+		if err != nil || !bytes.Equal(output, input) {
+			t.Fatalf("size %d: err = %v", size, err)
+		}
+		output, consumed, err := DecompressN(compressed, size, true)
+		if err != nil || !bytes.Equal(output, input) || consumed != len(compressed) {
+			t.Fatalf("size %d: consumed = %d of %d, err = %v", size, consumed, len(compressed), err)
+		}
+	}
+}
+
+func TestLevels(t *testing.T) {
+	// 重复的文本夹着伪随机数据
+	input := make([]byte, 100000)
+	seed := uint32(1)
+	for i := range input {
+		if i%5000 < 4000 {
+			input[i] = "the quick brown fox jumps over the lazy dog, "[(i/7+i)%45]
+		} else {
+			seed = seed*1103515245 + 12345
+			input[i] = byte(seed >> 16)
+		}
+	}
+
+	sizes := map[string]int{}
+	for _, test := range []struct {
+		name  string
+		level Level
+	}{
+		{"Level8", Level8},
+		{"Level9", Level9},
+		{"Level10", Level10},
+	} {
+		compressed, err := CompressWithLevel(input, test.level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output, err := Decompress(compressed)
+		if err != nil || !bytes.Equal(output, input) {
+			t.Fatalf("%s: err = %v", test.name, err)
+		}
+		sizes[test.name] = len(compressed)
+	}
+
+	if sizes["Level10"] > sizes["Level8"] {
+		t.Errorf("Level10 size %d is larger than Level8 size %d", sizes["Level10"], sizes["Level8"])
+	}
+}
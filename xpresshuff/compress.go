@@ -0,0 +1,321 @@
+package xpresshuff
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+const (
+	HASH_BITS  = 15
+	MAX_CHAIN  = 64
+	NICE_MATCH = 258
+)
+
+type token struct {
+	// literal: length == 0
+	literal uint8
+	length  int
+	offset  int
+}
+
+type Compressor struct {
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+
+	head []int
+	prev []int
+
+	// 位流: 与解压时的两个16位预读相对应, pointers[0]为正在填充的字, pointers[1]为下一个预留的字
+	bits     uint32
+	bitCount int
+	pointers [2]int
+}
+
+func (c *Compressor) hash(position int) int {
+	v := uint32(c.__input[position]) | uint32(c.__input[position+1])<<8 | uint32(c.__input[position+2])<<16
+	return int((v * 0x9E3779B1) >> (32 - HASH_BITS))
+}
+
+func (c *Compressor) insert(position int) {
+	if position+2 < len(c.__input) {
+		h := c.hash(position)
+		c.prev[position] = c.head[h]
+		c.head[h] = position
+	}
+}
+
+// find 匹配不跨越块的边界
+func (c *Compressor) find(position, end int) (offset, length int) {
+	if position+2 >= end {
+		return
+	}
+
+	maxLength := end - position
+	chain := MAX_CHAIN
+	for candidate := c.head[c.hash(position)]; candidate >= 0 && chain > 0; candidate = c.prev[candidate] {
+		if position-candidate > MAX_OFFSET {
+			break
+		}
+		l := 0
+		for l < maxLength && c.__input[position+l] == c.__input[candidate+l] {
+			l++
+		}
+		if l > length {
+			offset, length = position-candidate, l
+			if l >= NICE_MATCH || l == maxLength {
+				break
+			}
+		}
+		chain--
+	}
+
+	if length < MIN_MATCH {
+		return 0, 0
+	}
+	return
+}
+
+func (c *Compressor) parse(end int) []token {
+	var tokens []token
+	for c.__inputCursor < end {
+		offset, length := c.find(c.__inputCursor, end)
+		if length == 0 {
+			tokens = append(tokens, token{literal: c.__input[c.__inputCursor]})
+			c.insert(c.__inputCursor)
+			c.__inputCursor++
+			continue
+		}
+		tokens = append(tokens, token{length: length, offset: offset})
+		for i := 0; i < length; i++ {
+			c.insert(c.__inputCursor + i)
+		}
+		c.__inputCursor += length
+	}
+	return tokens
+}
+
+func offsetBits(offset int) int {
+	n := 0
+	for offset > 1 {
+		offset >>= 1
+		n++
+	}
+	return n
+}
+
+func (t *token) symbol() int {
+	if t.length == 0 {
+		return int(t.literal)
+	}
+	length := t.length - MIN_MATCH
+	if length > 15 {
+		length = 15
+	}
+	return 256 | offsetBits(t.offset)<<4 | length
+}
+
+// buildLengths 构建长度不超过MAX_CODE_BITS的哈夫曼码长, 超长时将频率减半后重试
+func buildLengths(frequencies []int) []uint8 {
+	lengths := make([]uint8, len(frequencies))
+
+	for {
+		type node struct {
+			frequency   int
+			symbol      int
+			left, right *node
+		}
+		var nodes []*node
+		for symbol, frequency := range frequencies {
+			if frequency > 0 {
+				nodes = append(nodes, &node{frequency: frequency, symbol: symbol})
+			}
+		}
+
+		if len(nodes) == 1 {
+			lengths[nodes[0].symbol] = 1
+			return lengths
+		}
+
+		// 两个有序队列构建哈夫曼树
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].frequency < nodes[j].frequency })
+		var merged []*node
+		pop := func() *node {
+			if len(merged) == 0 || (len(nodes) > 0 && nodes[0].frequency <= merged[0].frequency) {
+				n := nodes[0]
+				nodes = nodes[1:]
+				return n
+			}
+			n := merged[0]
+			merged = merged[1:]
+			return n
+		}
+		for len(nodes)+len(merged) > 1 {
+			a, b := pop(), pop()
+			merged = append(merged, &node{frequency: a.frequency + b.frequency, symbol: -1, left: a, right: b})
+		}
+
+		overflow := false
+		var walk func(n *node, depth int)
+		walk = func(n *node, depth int) {
+			if n.symbol >= 0 {
+				if depth > MAX_CODE_BITS {
+					overflow = true
+				}
+				lengths[n.symbol] = uint8(depth)
+				return
+			}
+			walk(n.left, depth+1)
+			walk(n.right, depth+1)
+		}
+		walk(merged[0], 0)
+
+		if !overflow {
+			return lengths
+		}
+		for i := range frequencies {
+			if frequencies[i] > 0 {
+				frequencies[i] = (frequencies[i] + 1) >> 1
+			}
+		}
+	}
+}
+
+// buildCodes 按(码长, 符号)顺序分配canonical编码
+func buildCodes(lengths []uint8) []uint16 {
+	codes := make([]uint16, len(lengths))
+	code := 0
+	for length := 1; length <= MAX_CODE_BITS; length++ {
+		for symbol, l := range lengths {
+			if int(l) == length {
+				codes[symbol] = uint16(code)
+				code++
+			}
+		}
+		code <<= 1
+	}
+	return codes
+}
+
+func (c *Compressor) reserve() int {
+	position := len(c.__output)
+	c.__output = append(c.__output, 0, 0)
+	return position
+}
+
+func (c *Compressor) writeBits(value uint32, n int) {
+	if n == 0 {
+		return
+	}
+	c.bitCount += n
+	c.bits |= value << uint(32-c.bitCount)
+	if c.bitCount > 16 {
+		binary.LittleEndian.PutUint16(c.__output[c.pointers[0]:], uint16(c.bits>>16))
+		c.bits <<= 16
+		c.bitCount -= 16
+		c.pointers[0] = c.pointers[1]
+		c.pointers[1] = c.reserve()
+	}
+}
+
+func (c *Compressor) flushBits() {
+	binary.LittleEndian.PutUint16(c.__output[c.pointers[0]:], uint16(c.bits>>16))
+	binary.LittleEndian.PutUint16(c.__output[c.pointers[1]:], uint16(c.bits))
+	c.bits = 0
+	c.bitCount = 0
+}
+
+func (c *Compressor) writeBlock(tokens []token, last bool) {
+	frequencies := make([]int, SYMBOLS)
+	for i := range tokens {
+		frequencies[tokens[i].symbol()]++
+	}
+	if last {
+		frequencies[EOF_SYMBOL]++
+	}
+	// 保证至少有两个符号, 避免出现只有一个长度为1的码
+	used := 0
+	for _, frequency := range frequencies {
+		if frequency > 0 {
+			used++
+		}
+	}
+	for symbol := 0; used < 2; symbol++ {
+		if frequencies[symbol] == 0 {
+			frequencies[symbol] = 1
+			used++
+		}
+	}
+
+	lengths := buildLengths(frequencies)
+	codes := buildCodes(lengths)
+
+	for i := 0; i < TABLE_SIZE; i++ {
+		c.__output = append(c.__output, lengths[2*i]|lengths[2*i+1]<<4)
+	}
+
+	c.pointers[0] = c.reserve()
+	c.pointers[1] = c.reserve()
+
+	for i := range tokens {
+		symbol := tokens[i].symbol()
+		c.writeBits(uint32(codes[symbol]), int(lengths[symbol]))
+		if symbol < 256 {
+			continue
+		}
+
+		length := tokens[i].length - MIN_MATCH
+		if length >= 15 {
+			length -= 15
+			if length < 255 {
+				c.__output = append(c.__output, byte(length))
+			} else {
+				c.__output = append(c.__output, 255)
+				length += 15
+				if length <= 0xFFFF {
+					c.__output = append(c.__output, byte(length), byte(length>>8))
+				} else {
+					c.__output = append(c.__output, 0, 0, byte(length), byte(length>>8), byte(length>>16), byte(length>>24))
+				}
+			}
+		}
+
+		bits := offsetBits(tokens[i].offset)
+		c.writeBits(uint32(tokens[i].offset-1<<bits), bits)
+	}
+
+	if last {
+		c.writeBits(uint32(codes[EOF_SYMBOL]), int(lengths[EOF_SYMBOL]))
+	}
+	c.flushBits()
+}
+
+func (c *Compressor) Compress() ([]byte, error) {
+	c.__output = make([]byte, 0, len(c.__input)/2+TABLE_SIZE+8)
+	c.head = make([]int, 1<<HASH_BITS)
+	for i := range c.head {
+		c.head[i] = -1
+	}
+	c.prev = make([]int, len(c.__input))
+
+	for {
+		end := c.__inputCursor + BLOCK_SIZE
+		if end > len(c.__input) {
+			end = len(c.__input)
+		}
+		tokens := c.parse(end)
+		last := end == len(c.__input)
+		c.writeBlock(tokens, last)
+		if last {
+			break
+		}
+	}
+
+	return c.__output, nil
+}
+
+func NewCompressor(input []byte) *Compressor {
+	return &Compressor{
+		__input: input,
+	}
+}
@@ -0,0 +1,168 @@
+package xpresshuff
+
+import "encoding/binary"
+
+type Decompressor struct {
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+
+	outputSize int
+
+	nextBits      uint32
+	extraBitCount int
+
+	lengths [SYMBOLS]uint8
+	table   []uint16
+}
+
+// buildTable 根据256字节的码长表构建2^15项的查找表
+func (d *Decompressor) buildTable() error {
+	if d.__inputCursor+TABLE_SIZE > len(d.__input) {
+		return ErrInvalidData
+	}
+	for i := 0; i < TABLE_SIZE; i++ {
+		b := d.__input[d.__inputCursor+i]
+		d.lengths[2*i] = b & 0x0F
+		d.lengths[2*i+1] = b >> 4
+	}
+	d.__inputCursor += TABLE_SIZE
+
+	if d.table == nil {
+		d.table = make([]uint16, 1<<MAX_CODE_BITS)
+	}
+	for i := range d.table {
+		d.table[i] = 0xFFFF
+	}
+
+	code := 0
+	for length := 1; length <= MAX_CODE_BITS; length++ {
+		for symbol := 0; symbol < SYMBOLS; symbol++ {
+			if int(d.lengths[symbol]) != length {
+				continue
+			}
+			start := code << (MAX_CODE_BITS - length)
+			end := start + 1<<(MAX_CODE_BITS-length)
+			if end > len(d.table) {
+				return ErrInvalidTable
+			}
+			for i := start; i < end; i++ {
+				d.table[i] = uint16(symbol)
+			}
+			code++
+		}
+		code <<= 1
+	}
+
+	return nil
+}
+
+// read16 超出输入的部分按0处理, 由输出大小限制结束
+func (d *Decompressor) read16() uint32 {
+	if d.__inputCursor+2 > len(d.__input) {
+		d.__inputCursor += 2
+		return 0
+	}
+	v := binary.LittleEndian.Uint16(d.__input[d.__inputCursor:])
+	d.__inputCursor += 2
+	return uint32(v)
+}
+
+func (d *Decompressor) consume(n int) {
+	d.nextBits <<= uint(n)
+	d.extraBitCount -= n
+	if d.extraBitCount < 0 {
+		d.nextBits |= d.read16() << uint(-d.extraBitCount)
+		d.extraBitCount += 16
+	}
+}
+
+func (d *Decompressor) Decompress() ([]byte, error) {
+	d.__output = make([]byte, 0, d.outputSize)
+
+	for len(d.__output) < d.outputSize {
+		if err := d.buildTable(); err != nil {
+			return nil, err
+		}
+
+		d.nextBits = d.read16()<<16 | d.read16()
+		d.extraBitCount = 16
+
+		blockEnd := len(d.__output) + BLOCK_SIZE
+		if blockEnd > d.outputSize {
+			blockEnd = d.outputSize
+		}
+
+		for len(d.__output) < blockEnd {
+			symbol := d.table[d.nextBits>>(32-MAX_CODE_BITS)]
+			if symbol == 0xFFFF {
+				return nil, ErrInvalidData
+			}
+			d.consume(int(d.lengths[symbol]))
+
+			if symbol < 256 {
+				d.__output = append(d.__output, byte(symbol))
+				continue
+			}
+
+			symbol -= 256
+			length := int(symbol & 0x0F)
+			offsetBits := int(symbol >> 4)
+
+			if length == 15 {
+				if d.__inputCursor >= len(d.__input) {
+					return nil, ErrInvalidData
+				}
+				length = int(d.__input[d.__inputCursor])
+				d.__inputCursor++
+				if length == 255 {
+					if d.__inputCursor+2 > len(d.__input) {
+						return nil, ErrInvalidData
+					}
+					length = int(binary.LittleEndian.Uint16(d.__input[d.__inputCursor:]))
+					d.__inputCursor += 2
+					if length == 0 {
+						if d.__inputCursor+4 > len(d.__input) {
+							return nil, ErrInvalidData
+						}
+						length = int(binary.LittleEndian.Uint32(d.__input[d.__inputCursor:]))
+						d.__inputCursor += 4
+					}
+					if length < 15 {
+						return nil, ErrInvalidData
+					}
+					length -= 15
+				}
+				length += 15
+			}
+			length += MIN_MATCH
+
+			offset := 1 << offsetBits
+			if offsetBits > 0 {
+				offset += int(d.nextBits >> uint(32-offsetBits))
+				d.consume(offsetBits)
+			}
+
+			if offset > len(d.__output) {
+				return nil, ErrInvalidData
+			}
+			if length > d.outputSize-len(d.__output) {
+				// 输出满了之后不会再解码符号, 所以EOF符号也不应该走到这里
+				return nil, ErrOutputOverflow
+			}
+			position := len(d.__output) - offset
+			for i := 0; i < length; i++ {
+				d.__output = append(d.__output, d.__output[position+i])
+			}
+		}
+	}
+
+	return d.__output, nil
+}
+
+func NewDecompressor(input []byte, outputSize int) *Decompressor {
+	return &Decompressor{
+		__input:    input,
+		outputSize: outputSize,
+	}
+}
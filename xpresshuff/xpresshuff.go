@@ -0,0 +1,34 @@
+package xpresshuff
+
+import "fmt"
+
+// MS-XCA 2.1 LZ77+Huffman
+// https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-xca/a8b7cb0a-92a6-4187-a23b-5e14273b96f8
+
+const (
+	BLOCK_SIZE    = 0x10000
+	TABLE_SIZE    = 256
+	SYMBOLS       = 512
+	MAX_CODE_BITS = 15
+	MAX_OFFSET    = 0xFFFF
+	MIN_MATCH     = 3
+
+	// EOF_SYMBOL 位于最后一个块的末尾, 即偏移1长度3的匹配
+	EOF_SYMBOL = 256
+)
+
+var (
+	ErrInvalidData  = fmt.Errorf("the input data is invalid")
+	ErrInvalidTable = fmt.Errorf("the huffman table is invalid")
+	// ErrOutputOverflow 匹配(或EOF符号)超出了外层格式给出的输出大小
+	ErrOutputOverflow = fmt.Errorf("the decompressed data exceeds the output size")
+)
+
+func Compress(input []byte) ([]byte, error) {
+	return NewCompressor(input).Compress()
+}
+
+// Decompress 数据流本身不包含解压后的大小, 需要由外层格式提供
+func Decompress(input []byte, outputSize int) ([]byte, error) {
+	return NewDecompressor(input, outputSize).Decompress()
+}
@@ -0,0 +1,37 @@
+package xpresshuff
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 100, BLOCK_SIZE, BLOCK_SIZE + 1, 3*BLOCK_SIZE - 7} {
+		input := make([]byte, size)
+		for i := range input {
+			input[i] = byte(i / 5 * 31 % 200)
+		}
+		compressed, err := Compress(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output, err := Decompress(compressed, len(input))
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(output, input) {
+			t.Fatalf("size %d: round-trip mismatch", size)
+		}
+	}
+}
+
+func TestDecompressOutputOverflow(t *testing.T) {
+	// 一个literal加一个长度99的匹配, 给出的输出大小只能容纳其中一部分
+	compressed, err := Compress(bytes.Repeat([]byte{'a'}, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Decompress(compressed, 50); err != ErrOutputOverflow {
+		t.Fatalf("err = %v, expected ErrOutputOverflow", err)
+	}
+}
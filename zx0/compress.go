@@ -0,0 +1,340 @@
+package zx0
+
+import "math/bits"
+
+// Block 最优解析链上的一个节点, index为该块覆盖的最后一个字节, offset为0表示字面量
+type Block struct {
+	chain  *Block
+	bits   int
+	index  int
+	offset int
+}
+
+func offsetCeiling(index, offsetLimit int) int {
+	if index > offsetLimit {
+		return offsetLimit
+	} else if index < INITIAL_OFFSET {
+		return INITIAL_OFFSET
+	}
+	return index
+}
+
+func eliasGammaBits(value int) int {
+	if value <= 1 {
+		return 1
+	}
+	return 2*bits.Len(uint(value)) - 1
+}
+
+// optimizeBlock 与Block相同, 在arena中以下标相互引用, 0表示nil
+type optimizeBlock struct {
+	chain      int32
+	ghostChain int32
+	references int32
+	bits       int32
+	index      int32
+	offset     int32
+}
+
+// optimizer 与参考实现的allocate/assign相同: 按引用计数回收不再被引用的节点
+type optimizer struct {
+	blocks []optimizeBlock
+	ghost  int32
+}
+
+func (o *optimizer) release(ptr int32) {
+	if ptr != 0 {
+		if o.blocks[ptr].references--; o.blocks[ptr].references == 0 {
+			o.blocks[ptr].ghostChain = o.ghost
+			o.ghost = ptr
+		}
+	}
+}
+
+func (o *optimizer) allocate(bits, index, offset int, chain int32) int32 {
+	var ptr int32
+	if o.ghost != 0 {
+		ptr = o.ghost
+		o.ghost = o.blocks[ptr].ghostChain
+		o.release(o.blocks[ptr].chain)
+	} else {
+		o.blocks = append(o.blocks, optimizeBlock{})
+		ptr = int32(len(o.blocks) - 1)
+	}
+	if chain != 0 {
+		o.blocks[chain].references++
+	}
+	o.blocks[ptr] = optimizeBlock{chain: chain, bits: int32(bits), index: int32(index), offset: int32(offset)}
+	return ptr
+}
+
+func (o *optimizer) assign(ptr *int32, chain int32) {
+	if chain != 0 {
+		o.blocks[chain].references++
+	}
+	o.release(*ptr)
+	*ptr = chain
+}
+
+// offsetState 每个offset的lastMatch和lastLiteral. 参考实现在每个位置为每个offset分配新的节点,
+// 这里只记录节点的值和它引用的前一个节点, 被其他节点引用时才分配(block), 解析结果不变
+type offsetState struct {
+	matchLength int
+
+	hasMatch   bool
+	matchIndex int
+	matchBits  int
+	matchChain int32
+	matchBlock int32
+
+	hasLiteral   bool
+	literalIndex int
+	literalBits  int
+	literalChain int32
+	literalBlock int32
+}
+
+func (o *optimizer) lastMatch(offset int, st *offsetState) int32 {
+	if st.matchBlock == 0 || int(o.blocks[st.matchBlock].index) != st.matchIndex {
+		o.assign(&st.matchBlock, o.allocate(st.matchBits, st.matchIndex, offset, st.matchChain))
+	}
+	return st.matchBlock
+}
+
+func (o *optimizer) lastLiteral(st *offsetState) int32 {
+	if st.literalBlock == 0 || int(o.blocks[st.literalBlock].index) != st.literalIndex {
+		o.assign(&st.literalBlock, o.allocate(st.literalBits, st.literalIndex, 0, st.literalChain))
+	}
+	return st.literalBlock
+}
+
+// Optimize 按照ZX0参考实现的方式计算最优解析, 返回链表的最后一个节点
+func Optimize(input []byte, offsetLimit int) *Block {
+	maxOffset := offsetCeiling(len(input)-1, offsetLimit)
+
+	// 下标0保留为nil
+	o := &optimizer{blocks: make([]optimizeBlock, 1, 1024)}
+
+	states := make([]offsetState, maxOffset+1)
+	optimal := make([]int32, len(input))
+	bestLength := make([]int, len(input)+1)
+	if len(input) > 2 {
+		bestLength[2] = 2
+	}
+	bitsOf := func(ptr int32) int { return int(o.blocks[ptr].bits) }
+
+	// start with fake block
+	states[INITIAL_OFFSET].hasMatch = true
+	states[INITIAL_OFFSET].matchIndex = -1
+	states[INITIAL_OFFSET].matchBits = -1
+
+	for index := 0; index < len(input); index++ {
+		bestLengthSize := 2
+		maxOffset = offsetCeiling(index, offsetLimit)
+
+		for offset := 1; offset <= maxOffset; offset++ {
+			st := &states[offset]
+
+			if index != 0 && index >= offset && input[index] == input[index-offset] {
+				// copy from last offset
+				lastBits := -1
+				if st.hasLiteral {
+					lastBits = st.literalBits + 1 + eliasGammaBits(index-st.literalIndex)
+				}
+
+				// copy from new offset
+				newBits, newLength := -1, 0
+				st.matchLength++
+				if st.matchLength > 1 {
+					if bestLengthSize < st.matchLength {
+						bits := bitsOf(optimal[index-bestLength[bestLengthSize]]) + eliasGammaBits(bestLength[bestLengthSize]-1)
+						for bestLengthSize < st.matchLength {
+							bestLengthSize++
+							bits2 := bitsOf(optimal[index-bestLengthSize]) + eliasGammaBits(bestLengthSize-1)
+							if bits2 <= bits {
+								bestLength[bestLengthSize] = bestLengthSize
+								bits = bits2
+							} else {
+								bestLength[bestLengthSize] = bestLength[bestLengthSize-1]
+							}
+						}
+					}
+
+					newLength = bestLength[st.matchLength]
+					newBits = bitsOf(optimal[index-newLength]) + 8 + eliasGammaBits((offset-1)/128+1) + eliasGammaBits(newLength-1)
+				}
+
+				// 参考实现先设置last offset的节点, new offset更短时再替换
+				var chain int32
+				bits := -1
+				if newBits >= 0 && (lastBits < 0 || lastBits > newBits) {
+					chain, bits = optimal[index-newLength], newBits
+				} else if lastBits >= 0 {
+					chain, bits = o.lastLiteral(st), lastBits
+				}
+				if bits >= 0 {
+					o.assign(&st.matchChain, chain)
+					st.hasMatch, st.matchIndex, st.matchBits = true, index, bits
+					if optimal[index] == 0 || bitsOf(optimal[index]) > bits {
+						o.assign(&optimal[index], o.lastMatch(offset, st))
+					}
+				}
+			} else {
+				// copy literals
+				st.matchLength = 0
+				if st.hasMatch {
+					length := index - st.matchIndex
+					bits := st.matchBits + 1 + eliasGammaBits(length) + length*8
+					if !st.hasLiteral || st.literalIndex < st.matchIndex {
+						// 新的字面量序列, 前一个节点为lastMatch
+						o.assign(&st.literalChain, o.lastMatch(offset, st))
+					}
+					st.hasLiteral, st.literalIndex, st.literalBits = true, index, bits
+					if optimal[index] == 0 || bitsOf(optimal[index]) > bits {
+						o.assign(&optimal[index], o.lastLiteral(st))
+					}
+				}
+			}
+		}
+	}
+
+	// 复制最优路径上的节点
+	var last, tail *Block
+	for ptr := optimal[len(input)-1]; ptr != 0; ptr = o.blocks[ptr].chain {
+		b := &Block{bits: bitsOf(ptr), index: int(o.blocks[ptr].index), offset: int(o.blocks[ptr].offset)}
+		if tail == nil {
+			last = b
+		} else {
+			tail.chain = b
+		}
+		tail = b
+	}
+	return last
+}
+
+type Compressor struct {
+	options Options
+
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+	__bitMask     uint8
+	__bitIndex    int
+	__backtrack   bool
+}
+
+func (c *Compressor) writeByte(b uint8) {
+	c.__output = append(c.__output, b)
+}
+
+func (c *Compressor) writeBit(bit bool) {
+	if c.__backtrack {
+		// 复用上一个offset LSB字节的最低位
+		if bit {
+			c.__output[len(c.__output)-1] |= 1
+		}
+		c.__backtrack = false
+		return
+	}
+
+	if c.__bitMask == 0 {
+		c.__bitMask = 128
+		c.__bitIndex = len(c.__output)
+		c.writeByte(0)
+	}
+
+	if bit {
+		c.__output[c.__bitIndex] |= c.__bitMask
+	}
+	c.__bitMask >>= 1
+}
+
+func (c *Compressor) writeInterlacedEliasGamma(value int, invert bool) {
+	i := 2
+	for ; i <= value; i <<= 1 {
+	}
+	i >>= 1
+
+	for i >>= 1; i > 0; i >>= 1 {
+		c.writeBit(c.options.Backward)
+		c.writeBit((value&i != 0) != invert)
+	}
+	c.writeBit(!c.options.Backward)
+}
+
+func (c *Compressor) Compress() ([]byte, error) {
+	if len(c.__input) == 0 {
+		return nil, nil
+	}
+
+	input := c.__input
+	if c.options.Backward {
+		input = reverse(input)
+	}
+
+	offsetLimit := c.options.OffsetLimit
+	if offsetLimit <= 0 || offsetLimit > MAX_OFFSET_ZX0 {
+		offsetLimit = MAX_OFFSET_ZX0
+	}
+
+	// 反转链表, 使其按输入顺序排列
+	var prev *Block
+	for optimal := Optimize(input, offsetLimit); optimal != nil; {
+		next := optimal.chain
+		optimal.chain = prev
+		prev = optimal
+		optimal = next
+	}
+
+	invert := !c.options.Classic && !c.options.Backward
+	lastOffset := INITIAL_OFFSET
+
+	c.__output = make([]byte, 0, len(input)/2)
+	c.__backtrack = true
+
+	for optimal := prev.chain; optimal != nil; prev, optimal = optimal, optimal.chain {
+		length := optimal.index - prev.index
+
+		if optimal.offset == 0 {
+			// copy literals
+			c.writeBit(false)
+			c.writeInterlacedEliasGamma(length, false)
+			c.__output = append(c.__output, input[c.__inputCursor:c.__inputCursor+length]...)
+		} else if optimal.offset == lastOffset {
+			// copy from last offset
+			c.writeBit(false)
+			c.writeInterlacedEliasGamma(length, false)
+		} else {
+			// copy from new offset
+			c.writeBit(true)
+			c.writeInterlacedEliasGamma((optimal.offset-1)/128+1, invert)
+			if c.options.Backward {
+				c.writeByte(byte(((optimal.offset - 1) % 128) << 1))
+			} else {
+				c.writeByte(byte((127 - (optimal.offset-1)%128) << 1))
+			}
+			c.__backtrack = true
+			c.writeInterlacedEliasGamma(length-1, false)
+			lastOffset = optimal.offset
+		}
+
+		c.__inputCursor += length
+	}
+
+	// end marker
+	c.writeBit(true)
+	c.writeInterlacedEliasGamma(256, invert)
+
+	if c.options.Backward {
+		return reverse(c.__output), nil
+	}
+
+	return c.__output, nil
+}
+
+func NewCompressor(input []byte, options Options) *Compressor {
+	return &Compressor{
+		options: options,
+		__input: input,
+	}
+}
@@ -0,0 +1,169 @@
+package zx0
+
+import (
+	"fmt"
+)
+
+var (
+	ErrInvalidData = fmt.Errorf("the input data is invalid")
+)
+
+type Decompressor struct {
+	options Options
+
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+	__bitMask     uint8
+	__bitValue    uint8
+	__lastByte    uint8
+	__backtrack   bool
+}
+
+func (d *Decompressor) readByte() (uint8, error) {
+	if d.__inputCursor >= len(d.__input) {
+		return 0, ErrInvalidData
+	}
+	d.__lastByte = d.__input[d.__inputCursor]
+	d.__inputCursor++
+	return d.__lastByte, nil
+}
+
+func (d *Decompressor) readBit() (bool, error) {
+	if d.__backtrack {
+		d.__backtrack = false
+		return d.__lastByte&1 != 0, nil
+	}
+
+	d.__bitMask >>= 1
+	if d.__bitMask == 0 {
+		d.__bitMask = 128
+		b, err := d.readByte()
+		if err != nil {
+			return false, err
+		}
+		d.__bitValue = b
+	}
+
+	return d.__bitValue&d.__bitMask != 0, nil
+}
+
+func (d *Decompressor) readInterlacedEliasGamma(invert bool) (int, error) {
+	value := 1
+	for {
+		bit, err := d.readBit()
+		if err != nil {
+			return 0, err
+		}
+		// 正向时0表示继续, 反向时1表示继续
+		if bit != d.options.Backward {
+			return value, nil
+		}
+
+		if bit, err = d.readBit(); err != nil {
+			return 0, err
+		}
+		value <<= 1
+		if bit != invert {
+			value |= 1
+		}
+
+		if value > 1<<30 {
+			return 0, ErrInvalidData
+		}
+	}
+}
+
+func (d *Decompressor) copyBytes(offset, length int) error {
+	if offset <= 0 || offset > len(d.__output) {
+		return ErrInvalidData
+	}
+	for i := 0; i < length; i++ {
+		d.__output = append(d.__output, d.__output[len(d.__output)-offset])
+	}
+	return nil
+}
+
+func (d *Decompressor) Decompress() ([]byte, error) {
+	if d.options.Backward {
+		d.__input = reverse(d.__input)
+	}
+
+	invert := !d.options.Classic && !d.options.Backward
+	lastOffset := INITIAL_OFFSET
+	d.__output = make([]byte, 0, len(d.__input)*2)
+
+	for {
+		// copy literals
+		length, err := d.readInterlacedEliasGamma(false)
+		if err != nil {
+			return nil, err
+		}
+		if d.__inputCursor+length > len(d.__input) {
+			return nil, ErrInvalidData
+		}
+		d.__output = append(d.__output, d.__input[d.__inputCursor:d.__inputCursor+length]...)
+		d.__inputCursor += length
+
+		newOffset, err := d.readBit()
+		if err != nil {
+			return nil, err
+		}
+
+		if !newOffset {
+			// copy from last offset
+			if length, err = d.readInterlacedEliasGamma(false); err != nil {
+				return nil, err
+			}
+			if err = d.copyBytes(lastOffset, length); err != nil {
+				return nil, err
+			}
+			if newOffset, err = d.readBit(); err != nil {
+				return nil, err
+			}
+		}
+
+		for newOffset {
+			// copy from new offset
+			msb, err := d.readInterlacedEliasGamma(invert)
+			if err != nil {
+				return nil, err
+			}
+			if msb == 256 {
+				// end marker
+				if d.options.Backward {
+					return reverse(d.__output), nil
+				}
+				return d.__output, nil
+			}
+
+			lsb, err := d.readByte()
+			if err != nil {
+				return nil, err
+			}
+			if d.options.Backward {
+				lastOffset = (msb-1)*128 + int(lsb>>1) + 1
+			} else {
+				lastOffset = msb*128 - int(lsb>>1)
+			}
+
+			d.__backtrack = true
+			if length, err = d.readInterlacedEliasGamma(false); err != nil {
+				return nil, err
+			}
+			if err = d.copyBytes(lastOffset, length+1); err != nil {
+				return nil, err
+			}
+			if newOffset, err = d.readBit(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+func NewDecompressor(input []byte, options Options) *Decompressor {
+	return &Decompressor{
+		options: options,
+		__input: input,
+	}
+}
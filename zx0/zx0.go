@@ -0,0 +1,48 @@
+package zx0
+
+const (
+	INITIAL_OFFSET = 1
+	MAX_OFFSET_ZX0 = 32640
+	MAX_OFFSET_ZX7 = 2176
+)
+
+type Options struct {
+	// Classic 使用ZX0 v1格式(offset MSB不取反)
+	Classic bool
+	// Backward 反向压缩, 用于从缓冲区末尾原地解压
+	Backward bool
+	// OffsetLimit 最大偏移量, 0表示MAX_OFFSET_ZX0, 设置为MAX_OFFSET_ZX7即为zx0的quick模式
+	OffsetLimit int
+}
+
+func CompressWithOptions(input []byte, options Options) ([]byte, error) {
+	return NewCompressor(input, options).Compress()
+}
+
+func Compress(input []byte) ([]byte, error) {
+	return CompressWithOptions(input, Options{})
+}
+
+func DecompressWithOptions(input []byte, options Options) ([]byte, error) {
+	return NewDecompressor(input, options).Decompress()
+}
+
+func Decompress(input []byte) ([]byte, error) {
+	return DecompressWithOptions(input, Options{})
+}
+
+func ZX7Compress(input []byte) ([]byte, error) {
+	return NewZX7Compressor(input).Compress()
+}
+
+func ZX7Decompress(input []byte) ([]byte, error) {
+	return NewZX7Decompressor(input).Decompress()
+}
+
+func reverse(bs []byte) []byte {
+	result := make([]byte, len(bs))
+	for i, b := range bs {
+		result[len(bs)-1-i] = b
+	}
+	return result
+}
@@ -0,0 +1,317 @@
+package zx0
+
+const (
+	ZX7_MAX_LEN = 65536
+	// zx7FullLengths 小于等于该长度时尝试每个长度, 更长时只尝试每个Elias gamma区间内最长的长度
+	zx7FullLengths = 256
+)
+
+type zx7Optimal struct {
+	bits   int
+	offset int
+	length int
+}
+
+func zx7CountBits(offset, length int) int {
+	if offset > 128 {
+		return 1 + 12 + eliasGammaBits(length-1)
+	}
+	return 1 + 8 + eliasGammaBits(length-1)
+}
+
+type ZX7Compressor struct {
+	__input    []byte
+	__output   []byte
+	__bitMask  uint8
+	__bitIndex int
+}
+
+func (c *ZX7Compressor) writeBit(bit bool) {
+	if c.__bitMask == 0 {
+		c.__bitMask = 128
+		c.__bitIndex = len(c.__output)
+		c.__output = append(c.__output, 0)
+	}
+	if bit {
+		c.__output[c.__bitIndex] |= c.__bitMask
+	}
+	c.__bitMask >>= 1
+}
+
+func (c *ZX7Compressor) writeEliasGamma(value int) {
+	i := 2
+	for ; i <= value; i <<= 1 {
+		c.writeBit(false)
+	}
+	for i >>= 1; i > 0; i >>= 1 {
+		c.writeBit(value&i != 0)
+	}
+}
+
+// optimize 以序列结尾位置为单位做动态规划, 候选匹配由前两个字节的哈希链给出
+func (c *ZX7Compressor) optimize() []zx7Optimal {
+	input := c.__input
+	optimal := make([]zx7Optimal, len(input))
+	matches := make([]int, 0x100*0x100)
+	matchSlots := make([]int, len(input))
+	// 记录每个offset最近一次匹配的结尾位置和(向前)长度, 避免重复比较
+	runEnd := make([]int, MAX_OFFSET_ZX7+1)
+	runLen := make([]int, MAX_OFFSET_ZX7+1)
+
+	// first byte is always literal
+	optimal[0].bits = 8
+
+	for i := 1; i < len(input); i++ {
+		optimal[i].bits = optimal[i-1].bits + 9
+		matchIndex := int(input[i-1])<<8 | int(input[i])
+		bestLen := 1
+
+		for match := matches[matchIndex]; match != 0 && bestLen < ZX7_MAX_LEN && bestLen < i; match = matchSlots[match] {
+			offset := i - match
+			if offset > MAX_OFFSET_ZX7 {
+				break
+			}
+
+			// 计算以i结尾、偏移为offset的最长匹配(不能覆盖第一个字节)
+			length := 2
+			if runEnd[offset] == i-1 && runLen[offset] >= 2 {
+				length = runLen[offset] + 1
+			} else {
+				for i-length-offset >= 0 && input[i-length] == input[i-length-offset] {
+					length++
+				}
+			}
+			if length > i {
+				length = i
+			}
+			runEnd[offset] = i
+			runLen[offset] = length
+
+			if length > ZX7_MAX_LEN {
+				length = ZX7_MAX_LEN
+			}
+
+			// offset越近代价越小, 较短的长度已被更近的offset处理过.
+			// 长度较长时逐个尝试是O(n^2)的(例如连续的0), 同一个gamma区间内代价相同, 只尝试区间内最长的
+			for bestLen < length {
+				next := bestLen + 1
+				if next > zx7FullLengths {
+					for next&(next-1) != 0 {
+						next += next & -next
+					}
+					if next > length {
+						next = length
+					}
+				}
+				bestLen = next
+
+				bits := optimal[i-bestLen].bits + zx7CountBits(offset, bestLen)
+				if optimal[i].bits > bits {
+					optimal[i].bits = bits
+					optimal[i].offset = offset
+					optimal[i].length = bestLen
+				}
+			}
+		}
+
+		matchSlots[i] = matches[matchIndex]
+		matches[matchIndex] = i
+	}
+
+	return optimal
+}
+
+func (c *ZX7Compressor) Compress() ([]byte, error) {
+	if len(c.__input) == 0 {
+		return nil, nil
+	}
+
+	optimal := c.optimize()
+
+	// 从结尾回溯出最优序列
+	sequence := make([]int, 0)
+	for cursor := len(c.__input) - 1; cursor > 0; {
+		sequence = append(sequence, cursor)
+		if optimal[cursor].length > 0 {
+			cursor -= optimal[cursor].length
+		} else {
+			cursor--
+		}
+	}
+
+	c.__output = make([]byte, 0, len(c.__input)/2)
+	// first byte is always literal
+	c.__output = append(c.__output, c.__input[0])
+
+	for i := len(sequence) - 1; i >= 0; i-- {
+		cursor := sequence[i]
+		if optimal[cursor].length == 0 {
+			c.writeBit(false)
+			c.__output = append(c.__output, c.__input[cursor])
+		} else {
+			c.writeBit(true)
+			c.writeEliasGamma(optimal[cursor].length - 1)
+
+			offset := optimal[cursor].offset - 1
+			if offset < 128 {
+				c.__output = append(c.__output, byte(offset))
+			} else {
+				offset -= 128
+				c.__output = append(c.__output, byte(offset&127|128))
+				for mask := 1024; mask > 127; mask >>= 1 {
+					c.writeBit(offset&mask != 0)
+				}
+			}
+		}
+	}
+
+	// end marker
+	c.writeBit(true)
+	for i := 0; i < 16; i++ {
+		c.writeBit(false)
+	}
+	c.writeBit(true)
+
+	return c.__output, nil
+}
+
+func NewZX7Compressor(input []byte) *ZX7Compressor {
+	return &ZX7Compressor{
+		__input: input,
+	}
+}
+
+type ZX7Decompressor struct {
+	__input       []byte
+	__inputCursor int
+	__output      []byte
+	__bitMask     uint8
+	__bitValue    uint8
+}
+
+func (d *ZX7Decompressor) readByte() (uint8, error) {
+	if d.__inputCursor >= len(d.__input) {
+		return 0, ErrInvalidData
+	}
+	d.__inputCursor++
+	return d.__input[d.__inputCursor-1], nil
+}
+
+func (d *ZX7Decompressor) readBit() (int, error) {
+	d.__bitMask >>= 1
+	if d.__bitMask == 0 {
+		d.__bitMask = 128
+		b, err := d.readByte()
+		if err != nil {
+			return 0, err
+		}
+		d.__bitValue = b
+	}
+
+	if d.__bitValue&d.__bitMask != 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// readEliasGamma 返回-1表示结束标记
+func (d *ZX7Decompressor) readEliasGamma() (int, error) {
+	i := 0
+	for {
+		bit, err := d.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if bit != 0 {
+			break
+		}
+		i++
+	}
+
+	if i > 15 {
+		return -1, nil
+	}
+
+	value := 1
+	for ; i > 0; i-- {
+		bit, err := d.readBit()
+		if err != nil {
+			return 0, err
+		}
+		value = value<<1 | bit
+	}
+	return value, nil
+}
+
+func (d *ZX7Decompressor) readOffset() (int, error) {
+	value, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+	if value < 128 {
+		return int(value), nil
+	}
+
+	high := 0
+	for i := 0; i < 4; i++ {
+		bit, err := d.readBit()
+		if err != nil {
+			return 0, err
+		}
+		high = high<<1 | bit
+	}
+	return (int(value&127) | high<<7) + 128, nil
+}
+
+func (d *ZX7Decompressor) Decompress() ([]byte, error) {
+	d.__output = make([]byte, 0, len(d.__input)*2)
+
+	// first byte is always literal
+	b, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	d.__output = append(d.__output, b)
+
+	for {
+		bit, err := d.readBit()
+		if err != nil {
+			return nil, err
+		}
+
+		if bit == 0 {
+			if b, err = d.readByte(); err != nil {
+				return nil, err
+			}
+			d.__output = append(d.__output, b)
+			continue
+		}
+
+		length, err := d.readEliasGamma()
+		if err != nil {
+			return nil, err
+		}
+		if length == -1 {
+			return d.__output, nil
+		}
+
+		offset, err := d.readOffset()
+		if err != nil {
+			return nil, err
+		}
+		offset++
+
+		if offset > len(d.__output) {
+			return nil, ErrInvalidData
+		}
+		for i := 0; i <= length; i++ {
+			d.__output = append(d.__output, d.__output[len(d.__output)-offset])
+		}
+	}
+}
+
+func NewZX7Decompressor(input []byte) *ZX7Decompressor {
+	return &ZX7Decompressor{
+		__input: input,
+	}
+}